@@ -0,0 +1,319 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// aliasesKey is the config file section of the user-defined aliases, e.g.
+	//
+	//	aliases:
+	//	  s: grp2cmd2 sub221 --sub221flag1=foo
+	aliasesKey = "aliases"
+
+	// aliasGroupID is the help group of the alias commands.
+	aliasGroupID = "aliases"
+
+	// annotationAlias is the cobra.Command annotation holding the expansion
+	// of an alias command.
+	annotationAlias = "cobravsviper/alias"
+)
+
+// prepareArgsE prepares the command line args before cobra parses them:
+//
+//   - the root persistent flags written before the first command are parsed
+//     into rootCmd (see parseRootFlagsE), so that the config file and the
+//     aliases can be found;
+//   - unless the first word names a built-in command, the plugins found on
+//     PATH are added to the tree (see addPluginCmds), so PATH is only scanned
+//     to run a plugin, for the help and for the completion;
+//   - unless the first word names a command of the tree, the aliases of the
+//     config file are added to the tree as alias commands, so they show up in
+//     the help and are completed, and the alias in the first word is expanded.
+func (c *cli) prepareArgsE(rootCmd *cobra.Command, args []string) ([]string, error) {
+	args, err := parseRootFlagsE(rootCmd, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// The hidden completion commands complete the last of the words that
+	// follow them: the word being completed is not expanded
+	words := args
+	completing := len(words) > 0 && (words[0] == cobra.ShellCompRequestCmd || words[0] == cobra.ShellCompNoDescRequestCmd)
+	if completing {
+		words = words[1:]
+	}
+	if len(words) > 0 && isCommand(rootCmd, words[0]) {
+		return args, nil
+	}
+
+	c.addPluginCmds(rootCmd)
+	if len(words) > 0 && isCommand(rootCmd, words[0]) {
+		return args, nil
+	}
+
+	aliases := c.loadAliases(rootCmd)
+	c.addAliasCmds(rootCmd, aliases)
+	if completing && len(words) <= 1 {
+		return args, nil
+	}
+	expanded, err := expandAliasesE(rootCmd, aliases, words)
+	if err != nil {
+		return nil, err
+	}
+	prefix := args[:len(args)-len(words)]
+	return append(append([]string{}, prefix...), expanded...), nil
+}
+
+// parseRootFlagsE parses the root persistent flags written before the first
+// command of args into rootCmd, and returns the args left, starting at the
+// first command. The parsing stops at the first argument that is not a root
+// persistent flag.
+//
+// Cobra does not parse the flags of the plugin and alias commands: the root
+// persistent flags written before them still apply.
+func parseRootFlagsE(rootCmd *cobra.Command, args []string) ([]string, error) {
+	flags := rootCmd.PersistentFlags()
+
+	i := 0
+	for i < len(args) {
+		arg := args[i]
+		var f *pflag.Flag
+		if name, ok := strings.CutPrefix(arg, "--"); ok && name != "" {
+			name, _, _ = strings.Cut(name, "=")
+			f = flags.Lookup(name)
+		} else if len(arg) >= 2 && arg[0] == '-' && arg[1] != '-' {
+			f = flags.ShorthandLookup(arg[1:2])
+			if f != nil && len(arg) > 2 && arg[2] != '=' {
+				// Grouped short flags, e.g. "-vq": leave them to cobra
+				f = nil
+			}
+		}
+		if f == nil {
+			break
+		}
+		// Also take the value of the flags written "--flag value" or "-f value"
+		if f.NoOptDefVal == "" && !strings.Contains(arg, "=") {
+			i++
+		}
+		i++
+	}
+	if i > len(args) {
+		i = len(args)
+	}
+
+	if err := flags.Parse(args[:i]); err != nil {
+		return nil, &UsageError{Err: err}
+	}
+	return args[i:], nil
+}
+
+// isCommand reports whether name is the name or an alias of a command of
+// rootCmd.
+func isCommand(rootCmd *cobra.Command, name string) bool {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAliases reads the aliases of the config file. The errors are ignored:
+// they are reported when the config is loaded again before the command runs.
+func (c *cli) loadAliases(rootCmd *cobra.Command) map[string]string {
+	if !c.configLoaded {
+		values, err := c.LoadDotEnvFilesE(rootCmd)
+		if err != nil {
+			c.log.Tracef("aliases not loaded: %v", err)
+			return nil
+		}
+		c.dotEnv = values
+		if err := c.ReadViperConfigE(rootCmd); err != nil {
+			c.log.Tracef("aliases not loaded: %v", err)
+			return nil
+		}
+		c.aliasConfigSources = configSources(rootCmd)
+	}
+	aliases, err := cast.ToStringMapStringE(c.v.Get(aliasesKey))
+	if err != nil {
+		c.log.Tracef("aliases not loaded: %v", err)
+		return nil
+	}
+	return aliases
+}
+
+// configSources returns the root persistent flags selecting the dotenv files
+// and the config file, to tell whether the layers loaded for the aliases are
+// still the ones selected once cobra parsed the whole command line.
+func configSources(rootCmd *cobra.Command) string {
+	var b strings.Builder
+	for _, name := range []string{"config", "dotenv", "env-file"} {
+		if f := rootCmd.PersistentFlags().Lookup(name); f != nil {
+			fmt.Fprintf(&b, "%s=%t:%s;", name, f.Changed, f.Value)
+		}
+	}
+	return b.String()
+}
+
+// addAliasCmds adds a command to rootCmd for each alias that does not collide
+// with a command of the tree, which always wins.
+func (c *cli) addAliasCmds(rootCmd *cobra.Command, aliases map[string]string) {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if isCommand(rootCmd, name) {
+			c.log.Warnf("alias %s is overshadowed by the command %s", name, name)
+			continue
+		}
+		if !rootCmd.ContainsGroup(aliasGroupID) {
+			rootCmd.AddGroup(&cobra.Group{ID: aliasGroupID, Title: "Alias Commands:"})
+		}
+		rootCmd.AddCommand(newAliasCmd(name, aliases[name]))
+	}
+}
+
+// newAliasCmd returns the command of the alias name. The alias is expanded
+// before cobra parses the command line, so the command only documents it in
+// the help and completion.
+func newAliasCmd(name, expansion string) *cobra.Command {
+	return &cobra.Command{
+		Use:                name,
+		Short:              fmt.Sprintf("Alias for %q", expansion),
+		GroupID:            aliasGroupID,
+		DisableFlagParsing: true,
+		Annotations:        map[string]string{annotationAlias: expansion},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("alias %s must be expanded before parsing the command line", name)
+		},
+	}
+}
+
+// expandAliasesE expands the alias named by the first word of words, then the
+// alias its expansion starts with, and so on. An alias expanding to itself,
+// directly or through other aliases, is a *ValidationError.
+func expandAliasesE(rootCmd *cobra.Command, aliases map[string]string, words []string) ([]string, error) {
+	var chain []string
+	for len(words) > 0 {
+		expansion, ok := aliases[words[0]]
+		if !ok || isBuiltinOrPlugin(rootCmd, words[0]) {
+			return words, nil
+		}
+		for _, name := range chain {
+			if name == words[0] {
+				return nil, &ValidationError{
+					Key:    aliasesKey + "." + chain[0],
+					Source: SourceConfig,
+					Err:    fmt.Errorf("alias recursion: %s -> %s", strings.Join(chain, " -> "), words[0]),
+				}
+			}
+		}
+		chain = append(chain, words[0])
+
+		expanded, err := splitShellWords(expansion)
+		if err != nil {
+			return nil, &ValidationError{Key: aliasesKey + "." + words[0], Source: SourceConfig, Err: err}
+		}
+		words = append(expanded, words[1:]...)
+	}
+	return words, nil
+}
+
+// isBuiltinOrPlugin reports whether name is a command of rootCmd other than
+// an alias command.
+func isBuiltinOrPlugin(rootCmd *cobra.Command, name string) bool {
+	for _, cmd := range rootCmd.Commands() {
+		if (cmd.Name() == name || cmd.HasAlias(name)) && cmd.Annotations[annotationAlias] == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitShellWords splits s into words like a POSIX shell does, without any
+// expansion: the words are separated by blanks, single quotes preserve the
+// literal value of the characters they enclose, double quotes preserve it
+// except for the backslash escaping '"', '\' and '$', and an unquoted
+// backslash preserves the literal value of the next character.
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case ch == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case ch == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case ch == '\\':
+			if i+1 >= len(s) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			word.WriteByte(s[i])
+			inWord = true
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
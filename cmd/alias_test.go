@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// TestSplitShellWords checks the alias expansions are split into words like a
+// POSIX shell does.
+func TestSplitShellWords(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"  grp2cmd2   sub221 ", []string{"grp2cmd2", "sub221"}, false},
+		{`--sub221flag1='a b'`, []string{"--sub221flag1=a b"}, false},
+		{`--sub221flag1="a \"b\" \c"`, []string{`--sub221flag1=a "b" \c`}, false},
+		{`a\ b '' c`, []string{"a b", "", "c"}, false},
+		{`'unterminated`, nil, true},
+		{`"unterminated`, nil, true},
+		{`trailing\`, nil, true},
+	}
+
+	for _, c := range cases {
+		got, err := splitShellWords(c.in)
+		if (err != nil) != c.wantErr || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitShellWords(%q) = %q, %v, expected %q, error? %v", c.in, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+// TestExecute_Alias checks the aliases of the config file are expanded before
+// cobra parses the command line, show up in the help and are completed.
+func TestExecute_Alias(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "cobravsviper.conf.yaml")
+	content := `
+aliases:
+  s: grp2cmd2 sub221 --sub221flag1=foo
+  t: s "--sub221flag3=with space"
+  loop1: loop2
+  loop2: loop1
+  version: grp1cmd1
+`
+	if err := os.WriteFile(config, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		exitCode int
+		want     []string
+	}{
+		{"alias", []string{"--log-format", "json", "s"}, ExitOK, []string{"sub221flag1: foo"}},
+		{"alias with args", []string{"--log-format=json", "s", "--sub221flag2", "bar"}, ExitOK, []string{"sub221flag1: foo", "sub221flag2: bar"}},
+		{"nested alias", []string{"--log-format", "json", "t"}, ExitOK, []string{"sub221flag1: foo", "sub221flag3: with space"}},
+		{"recursion", []string{"loop1"}, ExitValidation, []string{"alias recursion: loop1 -> loop2 -> loop1"}},
+		{"command wins", []string{"version", "-o", "json"}, ExitOK, []string{`"buildPlatform"`}},
+		{"help", []string{"--help"}, ExitOK, []string{"Alias Commands:", `Alias for "grp2cmd2 sub221 --sub221flag1=foo"`}},
+		{"complete alias", []string{"__complete", ""}, ExitOK, []string{"s\tAlias for"}},
+		{"complete alias flags", []string{"__complete", "s", "--sub221flag"}, ExitOK, []string{"--sub221flag2"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			result := Execute(context.Background(), c.args, IOStreams{In: strings.NewReader(""), Out: &out, Err: &errOut}, map[string]string{"COBRAVSVIPER_CONFIG": config})
+
+			if result.ExitCode != c.exitCode {
+				t.Errorf("Execute(%q) = %+v, expected exit code %d", c.args, result, c.exitCode)
+			}
+			output := out.String() + errOut.String()
+			for _, want := range c.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("missing %q in output %q", want, output)
+				}
+			}
+		})
+	}
+}
+
+// openCountFs counts the files opened on its Fs.
+type openCountFs struct {
+	afero.Fs
+	opened map[string]int
+}
+
+func (fs *openCountFs) Open(name string) (afero.File, error) {
+	fs.opened[name]++
+	return fs.Fs.Open(name)
+}
+
+// TestExecute_AliasConfigParsedOnce checks the dotenv files and the config
+// file read to find the aliases are not parsed again to run the command,
+// unless the command line parsed by cobra selects other files.
+func TestExecute_AliasConfigParsedOnce(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		want map[string]int
+	}{
+		{"alias", []string{"--config", testConfigFile, "--env-file", "/test.env", "g"}, map[string]int{testConfigFile: 1, "/test.env": 1}},
+		{"config after the alias", []string{"--env-file", "/test.env", "g", "--config", "/other.yaml"}, map[string]int{testConfigFile: 1, "/test.env": 2, "/other.yaml": 1}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := &openCountFs{Fs: newTestFs(t, "aliases:\n  g: grp1cmd1\n"), opened: map[string]int{}}
+			for file, content := range map[string]string{"/test.env": "COBRAVSVIPER_CONFIG=" + testConfigFile + "\n", "/other.yaml": "{}\n"} {
+				if err := afero.WriteFile(fs.Fs, file, []byte(content), 0o644); err != nil {
+					t.Fatalf("failed to write %s: %v", file, err)
+				}
+			}
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: map[string]string{}, Fs: fs})
+			if result := c.execute(context.Background(), newRootCmd(c), tc.args); result.ExitCode != ExitOK {
+				t.Fatalf("exit code %d, expected %d: %v", result.ExitCode, ExitOK, result.Err)
+			}
+			for file, want := range tc.want {
+				if got := fs.opened[file]; got != want {
+					t.Errorf("%s opened %d times, expected %d", file, got, want)
+				}
+			}
+		})
+	}
+}
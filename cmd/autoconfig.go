@@ -0,0 +1,111 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// AnnotationSkipConfig is the cobra.Command annotation opting a command out of
+// the config: when set to "true", the flags of the command are not bound to
+// Viper and ignore the env variables, dotenv files and config file.
+//
+// Example:
+//
+//	cmd.Annotations = map[string]string{AnnotationSkipConfig: "true"}
+const AnnotationSkipConfig = "cobravsviper/skip-config"
+
+// SetConfigTarget registers the struct the config section of cmd is
+// unmarshalled into when cmd or one of its descendants runs (see
+// InitCommandConfigE). A command without target still has its flags wired to
+// Viper.
+func (c *cli) SetConfigTarget(cmd *cobra.Command, target any) {
+	c.configTargets[cmd] = target
+}
+
+// skipConfig reports whether cmd opted out of the config with the
+// AnnotationSkipConfig annotation.
+func skipConfig(cmd *cobra.Command) bool {
+	return cmd.Annotations[AnnotationSkipConfig] == "true"
+}
+
+// InitCommandConfigE wires Viper for any command of the tree, with no code in
+// the command itself: it binds the flags of cmd to their config key and env
+// variable, unmarshals the config section of cmd into its config target if any
+// (see SetConfigTarget), then sets the local flags of cmd that are not set on
+// the command line to their value resolved from the env variables, dotenv
+// files or config file. So the flag variables of a command follow the
+// priority chain even without a config target.
+//
+// It is run by the root command for each command of the path of the executed
+// command, from the root command to the executed command, so the commands
+// added to the tree later are wired as well. It does nothing for the commands
+// annotated with AnnotationSkipConfig.
+func (c *cli) InitCommandConfigE(cmd *cobra.Command) error {
+	if skipConfig(cmd) {
+		c.logger(cmd).Trace("config skipped")
+		return nil
+	}
+	if err := c.InitViperSubCmdE(cmd, c.configTargets[cmd]); err != nil {
+		return err
+	}
+	return c.applyConfigToFlagsE(cmd)
+}
+
+// applyConfigToFlagsE sets the local flags of cmd, not set on the command
+// line, to their value resolved from the env variables, dotenv files or config
+// file. The flags keep their Changed state, so cobra still only considers the
+// flags set on the command line for the required and mutually exclusive flags.
+//
+// It returns a *ValidationError for each value that is invalid for its flag.
+func (c *cli) applyConfigToFlagsE(cmd *cobra.Command) error {
+	var errs []error
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		key := SectionPath(cmd) + "." + f.Name
+		source := c.sources[key]
+		if source != SourceEnv && source != SourceDotEnv && source != SourceConfig {
+			return
+		}
+
+		var err error
+		value := c.v.Get(key)
+		if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+			if s, isString := value.(string); isString {
+				err = sliceValue.Replace(strings.Split(s, ","))
+			} else {
+				err = sliceValue.Replace(cast.ToStringSlice(value))
+			}
+		} else {
+			err = f.Value.Set(cast.ToString(value))
+		}
+		if err != nil {
+			errs = append(errs, &ValidationError{Key: key, Source: source, Err: err})
+		}
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// TestInitCommandConfigE_FutureCommand checks a command added to the tree
+// without any Viper code gets its flag variables from the env variables and
+// the config file, unless it opted out with AnnotationSkipConfig.
+func TestInitCommandConfigE_FutureCommand(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		args        []string
+		wantName    string
+		wantTags    []string
+	}{
+		{"defaults overridden", nil, nil, "value from env", []string{"a", "b"}},
+		{"flags win", nil, []string{"--name", "value from cli", "--tag", "c"}, "value from cli", []string{"c"}},
+		{"opt-out", map[string]string{AnnotationSkipConfig: "true"}, nil, "value from default", nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := newTestFs(t, `
+cobravsviper:
+  future:
+    name: value from config
+    tag: [a, b]
+`)
+			c := newCLI(Options{
+				IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}},
+				Env: map[string]string{
+					"COBRAVSVIPER_CONFIG":      testConfigFile,
+					"COBRAVSVIPER_FUTURE_NAME": "value from env",
+				},
+				Fs: fs,
+			})
+			rootCmd := newRootCmd(c)
+
+			var name string
+			var tags []string
+			futureCmd := &cobra.Command{
+				Use:         "future",
+				Annotations: tc.annotations,
+				RunE:        func(cmd *cobra.Command, args []string) error { return nil },
+			}
+			futureCmd.Flags().StringVar(&name, "name", "value from default", "name")
+			futureCmd.Flags().StringSliceVar(&tags, "tag", nil, "tags")
+			rootCmd.AddCommand(futureCmd)
+
+			rootCmd.SetArgs(append([]string{"future"}, tc.args...))
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if name != tc.wantName || strings.Join(tags, ",") != strings.Join(tc.wantTags, ",") {
+				t.Errorf("got name %q tags %v, expected name %q tags %v", name, tags, tc.wantName, tc.wantTags)
+			}
+		})
+	}
+}
+
+// TestInitCommandConfigE_InvalidValue checks a config value invalid for the
+// type of a flag is reported as a *ValidationError.
+func TestInitCommandConfigE_InvalidValue(t *testing.T) {
+	c := newCLI(Options{
+		IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}},
+		Env:       map[string]string{"COBRAVSVIPER_FUTURE_COUNT": "many"},
+		Fs:        afero.NewMemMapFs(),
+	})
+	rootCmd := newRootCmd(c)
+	futureCmd := &cobra.Command{Use: "future", RunE: func(cmd *cobra.Command, args []string) error { return nil }}
+	futureCmd.Flags().Int("count", 1, "count")
+	rootCmd.AddCommand(futureCmd)
+
+	rootCmd.SetArgs([]string{"future"})
+	err := rootCmd.Execute()
+	if ExitCode(err) != ExitValidation || !strings.Contains(err.Error(), "cobravsviper.future.count (from env)") {
+		t.Errorf("got error %v, expected a validation error for cobravsviper.future.count from env", err)
+	}
+}
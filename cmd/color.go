@@ -0,0 +1,89 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Modes of --color.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// colorModes are the values of --color, in the order they are documented and
+// completed.
+var colorModes = []string{colorAuto, colorAlways, colorNever}
+
+// validateColorModeE returns an error if mode is not one of the colorModes.
+func validateColorModeE(mode string) error {
+	if !slices.Contains(colorModes, mode) {
+		return fmt.Errorf("unknown color mode %q, expected one of %s", mode, strings.Join(colorModes, ", "))
+	}
+	return nil
+}
+
+// completeColorModes completes --color with the colorModes.
+func completeColorModes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return colorModes, cobra.ShellCompDirectiveNoFileComp
+}
+
+// colorEnabled reports whether the output written to w, e.g. the logs on the
+// error stream or a table on the output stream, is colored. With --color
+// always or never, it is or is not. With --color auto, the default, it is not
+// if NO_COLOR is set to a non empty value, it is if CLICOLOR_FORCE is set to a
+// value other than 0, otherwise it is if w is a terminal.
+func (c *cli) colorEnabled(w io.Writer) bool {
+	switch c.vprFlgsRoot.Color {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	if value, ok := c.lookupEnv("NO_COLOR"); ok && value != "" {
+		return false
+	}
+	if value, ok := c.lookupEnv("CLICOLOR_FORCE"); ok && value != "" && value != "0" {
+		return true
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w, or the writer wrapped by w if it is a
+// *syncWriter, is a terminal.
+func isTerminal(w io.Writer) bool {
+	if sw, ok := w.(*syncWriter); ok {
+		w = sw.w
+	}
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestColorEnabled checks the color policy: --color, then NO_COLOR, then
+// CLICOLOR_FORCE, then whether the output is a terminal.
+func TestColorEnabled(t *testing.T) {
+	cases := []struct {
+		mode string
+		env  map[string]string
+		want bool
+	}{
+		{"", nil, false},
+		{colorAuto, nil, false},
+		{colorAuto, map[string]string{"CLICOLOR_FORCE": "1"}, true},
+		{colorAuto, map[string]string{"CLICOLOR_FORCE": "0"}, false},
+		{colorAuto, map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, false},
+		{colorAuto, map[string]string{"NO_COLOR": ""}, false},
+		{colorAlways, map[string]string{"NO_COLOR": "1"}, true},
+		{colorNever, map[string]string{"CLICOLOR_FORCE": "1"}, false},
+	}
+
+	for _, tc := range cases {
+		c := newCLI(Options{Env: tc.env})
+		c.vprFlgsRoot.Color = tc.mode
+		if got := c.colorEnabled(&bytes.Buffer{}); got != tc.want {
+			t.Errorf("colorEnabled() with --color=%q and env %v = %t, expected %t", tc.mode, tc.env, got, tc.want)
+		}
+	}
+}
+
+// TestColor checks the console logs are colored following --color, set with
+// the flag, the env or the config file, and that unknown modes are validation
+// errors.
+func TestColor(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		env      map[string]string
+		args     []string
+		exitCode int
+		colored  bool
+	}{
+		{"not a terminal", "", nil, nil, ExitOK, false},
+		{"flag", "", map[string]string{"NO_COLOR": "1"}, []string{"--color", "always"}, ExitOK, true},
+		{"env", "", map[string]string{"COBRAVSVIPER_COLOR": "always"}, nil, ExitOK, true},
+		{"config", "cobravsviper:\n  color: never\n", map[string]string{"CLICOLOR_FORCE": "1"}, nil, ExitOK, false},
+		{"CLICOLOR_FORCE", "", map[string]string{"CLICOLOR_FORCE": "1"}, nil, ExitOK, true},
+		{"unknown mode", "", nil, []string{"--color", "sometimes"}, ExitValidation, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			var logs bytes.Buffer
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &logs}, Env: env, Fs: newTestFs(t, tc.config)})
+			result := c.execute(context.Background(), newRootCmd(c), append(append([]string{"-v"}, tc.args...), "grp2cmd2", "sub221"))
+			if result.ExitCode != tc.exitCode {
+				t.Fatalf("got %+v, expected exit code %d", result, tc.exitCode)
+			}
+			if tc.exitCode != ExitOK {
+				return
+			}
+			if colored := strings.Contains(logs.String(), "\x1b["); colored != tc.colored {
+				t.Errorf("got colored logs %t, expected %t:\n%s", colored, tc.colored, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/nicop311/cobravsviper/pkg/output"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// newConfigCmd returns the config command and its subcommands
+func newConfigCmd(c *cli) *cobra.Command {
+	// configCmd represents the config command
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Read and edit the config file",
+		Long: `Read and edit the config file found like for any other command: the file set
+with --config or COBRAVSVIPER_CONFIG, or cobravsviper.conf.* in the home directory
+or in ~/.config/cobravsviper.
+
+The keys are the flags of the commands of the tree, prefixed with the path of the
+command, e.g. "grp2cmd2.sub221.sub221flag1" or "log-level", and the log level of
+each command, e.g. "grp2cmd2.sub221.log-level". The root section "cobravsviper."
+may be omitted.
+
+The edits preserve the comments, the key order and the format (YAML, TOML or
+JSON) of the file. The sections must be YAML block mappings or TOML tables: the
+edits fail on multi-document YAML files, on YAML sections written as flow
+mappings or aliases, on TOML arrays of tables, and on TOML sections written as
+inline tables or dotted keys. The JSON lists are written on a single line.`,
+		Annotations: map[string]string{AnnotationSkipConfig: "true"},
+	}
+
+	getCmd := &cobra.Command{
+		Use:               "get <key>",
+		Short:             "Print the value of a key of the config file",
+		Args:              cobra.ExactArgs(1),
+		Annotations:       map[string]string{AnnotationSkipConfig: "true"},
+		ValidArgsFunction: completeConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, _, err := resolveConfigKeyE(cmd.Root(), args[0])
+			if err != nil {
+				return err
+			}
+			file := c.v.ConfigFileUsed()
+			if file == "" {
+				return &ConfigNotFoundError{Err: errors.New("no config file found")}
+			}
+			v := viper.New()
+			v.SetFs(c.opts.Fs)
+			v.SetConfigFile(file)
+			if err := v.ReadInConfig(); err != nil {
+				return newConfigParseError(c.opts.Fs, file, err)
+			}
+			if !v.IsSet(key) {
+				return fmt.Errorf("%s is not set in the config file %s", key, file)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), envValue(v.Get(key)))
+			return nil
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set the value of a key in the config file",
+		Long: `Set the value of a key in the config file, creating the missing sections. The
+value must be valid for the flag of the key, the list values being separated by
+commas.
+
+Without config file, ~/.config/cobravsviper/cobravsviper.conf.yaml is created.`,
+		Args:              cobra.ExactArgs(2),
+		Annotations:       map[string]string{AnnotationSkipConfig: "true"},
+		ValidArgsFunction: completeConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, f, err := resolveConfigKeyE(cmd.Root(), args[0])
+			if err != nil {
+				return err
+			}
+			value, err := configValueE(f.Value, args[1])
+			if err != nil {
+				return &ValidationError{Key: key, Err: err}
+			}
+			return c.editConfigFileE(cmd, true, func(format string, content []byte) ([]byte, error) {
+				return setConfigValueE(format, content, strings.Split(key, "."), value)
+			})
+		},
+	}
+
+	unsetCmd := &cobra.Command{
+		Use:               "unset <key>",
+		Short:             "Remove a key from the config file",
+		Args:              cobra.ExactArgs(1),
+		Annotations:       map[string]string{AnnotationSkipConfig: "true"},
+		ValidArgsFunction: completeConfigKeys,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, _, err := resolveConfigKeyE(cmd.Root(), args[0])
+			if err != nil {
+				return err
+			}
+			return c.editConfigFileE(cmd, false, func(format string, content []byte) ([]byte, error) {
+				out, found, err := unsetConfigValueE(format, content, strings.Split(key, "."))
+				if err == nil && !found {
+					c.logger(cmd).Warnf("%s is not set in the config file", key)
+				}
+				return out, err
+			})
+		},
+	}
+
+	var from, to string
+	var strict bool
+	convertCmd := &cobra.Command{
+		Use:   "convert [file]",
+		Short: "Convert a config file to another format",
+		Long: `Print the config file, or the config file found, converted to another format:
+yaml, toml, json or hcl. The nested sections and the types of the values are
+preserved, the keys are sorted.
+
+The values the target format cannot represent as is are reported: the comments,
+the null values in TOML and HCL, the dates in JSON and HCL...
+
+Examples:
+  # convert the config file found to TOML
+  cobravsviper config convert --to toml > cobravsviper.conf.toml`,
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{AnnotationSkipConfig: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log := c.logger(cmd)
+			for _, format := range []string{from, to} {
+				if format != "auto" && !slices.Contains(convertFormats, format) {
+					return &UsageError{Err: fmt.Errorf("unsupported config format %q, expected one of %s", format, strings.Join(convertFormats, ", "))}
+				}
+			}
+			if to == "auto" {
+				return &UsageError{Err: errors.New("--to cannot be auto")}
+			}
+			file := c.v.ConfigFileUsed()
+			if len(args) > 0 {
+				file = args[0]
+			}
+			if file == "" {
+				return &ConfigNotFoundError{Err: errors.New("no config file found")}
+			}
+			content, err := afero.ReadFile(c.opts.Fs, file)
+			if err != nil {
+				return &ConfigNotFoundError{File: file, Err: err}
+			}
+			if from == "auto" {
+				if from, err = detectConfigFormat(file, content); err != nil {
+					return &ConfigParseError{File: file, Err: err}
+				}
+			}
+
+			out, lossy, err := convertConfigE(from, to, content)
+			if err != nil {
+				return &ConfigParseError{File: file, Err: err}
+			}
+			for _, item := range lossy {
+				log.Warnf("lossy conversion to %s: %s", to, item)
+			}
+			if strict && len(lossy) > 0 {
+				return &ValidationError{Key: lossy[0].Key, Source: SourceConfig, Err: fmt.Errorf("%d lossy conversion(s) to %s, first: %s", len(lossy), to, lossy[0])}
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		},
+	}
+	convertCmd.Flags().StringVar(&from, "from", "auto", "Format of the config file. One of 'auto', 'yaml', 'toml', 'json' or 'hcl'. 'auto' detects the format from the file extension, or from the content.")
+	convertCmd.Flags().StringVar(&to, "to", "", "Format to convert the config file to. One of 'yaml', 'toml', 'json' or 'hcl'.")
+	convertCmd.Flags().BoolVar(&strict, "strict", false, "Fail instead of reporting the values that cannot be converted losslessly.")
+	convertCmd.MarkFlagRequired("to")
+	convertCmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return append([]string{"auto"}, convertFormats...), cobra.ShellCompDirectiveNoFileComp
+	})
+	convertCmd.RegisterFlagCompletionFunc("to", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return convertFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	var command, diffFormat string
+	diffCmd := &cobra.Command{
+		Use:   "diff <file> [file]",
+		Short: "Compare the config resolved from two config files",
+		Long: `Compare the config resolved from two config files, or from the effective config
+and a config file when only one file is given. Each key is resolved through
+the env variables, the dotenv files, the config file and the defaults, like the
+commands do, so a value set in both files but overridden by an env variable is
+no difference. The files may have different formats.
+
+The keys are the ones of the whole tree, or of the command given with
+--command, including its inherited flags.
+
+Examples:
+  # what the new config file changes for the sub221 command
+  cobravsviper config diff old.yaml new.toml --command "grp2cmd2 sub221"
+
+  # what the config file changes compared to the effective config
+  cobravsviper config diff new.yaml -o json`,
+		Args:        cobra.RangeArgs(1, 2),
+		Annotations: map[string]string{AnnotationSkipConfig: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			diff := ConfigDiff{A: effectiveConfig, B: args[0]}
+			files := []string{"", args[0]}
+			if len(args) == 2 {
+				diff.A, diff.B = args[0], args[1]
+				files = args
+			}
+			words := strings.Fields(strings.ReplaceAll(command, ".", " "))
+			if len(words) > 0 {
+				diff.Command = strings.Join(words, " ")
+			}
+
+			a, err := c.resolveConfigE(cmd.Root(), files[0], words)
+			if err != nil {
+				return err
+			}
+			b, err := c.resolveConfigE(cmd.Root(), files[1], words)
+			if err != nil {
+				return err
+			}
+			diff.Changes = diffConfig(a, b)
+			return writeConfigDiffE(cmd.OutOrStdout(), diff, diffFormat, output.Options{Color: c.colorEnabled(cmd.OutOrStdout())})
+		},
+	}
+	diffCmd.Flags().StringVar(&command, "command", "", "Command to compare the config of, e.g. \"grp2cmd2 sub221\". All the commands by default.")
+	diffCmd.Flags().StringVarP(&diffFormat, "output", "o", "unified", "Format of the diff: unified, or one of the output formats "+strings.Join(output.Names(output.Formats), ", ")+".")
+	diffCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		formats, directive := completeOutputFormats(output.Formats)(cmd, args, toComplete)
+		if formats == nil {
+			return nil, directive
+		}
+		return append([]string{"unified\tone line per changed key, like diff -u"}, formats...), directive
+	})
+	diffCmd.RegisterFlagCompletionFunc("command", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var paths []string
+		var walk func(cmd *cobra.Command)
+		walk = func(cmd *cobra.Command) {
+			for _, sub := range cmd.Commands() {
+				if skipConfig(sub) || sub.Annotations[annotationPlugin] != "" || sub.Annotations[annotationAlias] != "" {
+					continue
+				}
+				paths = append(paths, strings.TrimPrefix(sub.CommandPath(), cmd.Root().Name()+" "))
+				walk(sub)
+			}
+		}
+		walk(cmd.Root())
+		return paths, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	configCmd.AddCommand(getCmd, setCmd, unsetCmd, convertCmd, diffCmd)
+
+	return configCmd
+}
+
+// editConfigFileE applies edit to the content of the config file of the tree
+// and writes the result back, keeping the file permissions. Without config
+// file, it fails, or creates ~/.config/cobravsviper/cobravsviper.conf.yaml if
+// create is set.
+func (c *cli) editConfigFileE(cmd *cobra.Command, create bool, edit func(format string, content []byte) ([]byte, error)) error {
+	file := c.v.ConfigFileUsed()
+	var content []byte
+	mode := fs.FileMode(0o644)
+	switch info, err := c.opts.Fs.Stat(file); {
+	case file != "" && err == nil:
+		mode = info.Mode().Perm()
+		if content, err = afero.ReadFile(c.opts.Fs, file); err != nil {
+			return err
+		}
+	case file != "" && !errors.Is(err, fs.ErrNotExist):
+		return err
+	case file != "" && !create:
+		return &ConfigNotFoundError{File: file, Err: err}
+	case file == "" && !create:
+		return &ConfigNotFoundError{Err: errors.New("no config file found")}
+	case file == "":
+		home, err := c.homeDir()
+		if err != nil {
+			return fmt.Errorf("failed to find home directory: %w", err)
+		}
+		file = filepath.Join(home, ".config", "cobravsviper", "cobravsviper.conf.yaml")
+	}
+
+	format, err := configFormat(file)
+	if err != nil {
+		return err
+	}
+	out, err := edit(format, content)
+	if err != nil {
+		return fmt.Errorf("failed to edit the config file %s: %w", file, err)
+	}
+	if string(out) == string(content) && content != nil {
+		return nil
+	}
+	if err := c.opts.Fs.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	if err := afero.WriteFile(c.opts.Fs, file, out, mode); err != nil {
+		return err
+	}
+	c.logger(cmd).Infof("config file %s updated", file)
+	return nil
+}
+
+// isConfigLocationFlag reports whether the root persistent flag name locates
+// the config layers, and so cannot be set in the config file itself.
+func isConfigLocationFlag(name string) bool {
+	return name == "config" || name == "env-file" || name == "dotenv"
+}
+
+// configKeys returns the config keys of the tree of rootCmd, with their flag:
+// the flags of the commands prefixed with the section of the command declaring
+// them (see SectionPath), and the log-level key of the section of each command
+// but the root and the hidden ones (see commandLogLevelFlag). The plugin and alias commands
+// and the commands annotated with AnnotationSkipConfig have no key.
+func configKeys(rootCmd *cobra.Command) map[string]*pflag.Flag {
+	keys := map[string]*pflag.Flag{}
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if skipConfig(cmd) || cmd.Annotations[annotationPlugin] != "" || cmd.Annotations[annotationAlias] != "" {
+			return
+		}
+		cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+			if f.Name == "help" || (cmd == rootCmd && isConfigLocationFlag(f.Name)) || persistentFlagOwner(cmd, f.Name) != cmd {
+				return
+			}
+			keys[SectionPath(cmd)+"."+f.Name] = f
+		})
+		if cmd != rootCmd && !cmd.Hidden {
+			keys[SectionPath(cmd)+"."+commandLogLevelKey] = commandLogLevelFlag(cmd)
+		}
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(rootCmd)
+	return keys
+}
+
+// resolveConfigKeyE returns the full config key of key, which may omit the
+// root section, and its flag. An unknown key is a *ValidationError.
+func resolveConfigKeyE(rootCmd *cobra.Command, key string) (string, *pflag.Flag, error) {
+	keys := configKeys(rootCmd)
+	if f, ok := keys[key]; ok {
+		return key, f, nil
+	}
+	full := SectionPath(rootCmd) + "." + key
+	if f, ok := keys[full]; ok {
+		return full, f, nil
+	}
+	return "", nil, &ValidationError{Key: key, Err: errors.New("unknown key, expected <command path>.<flag> or <command path>.log-level, e.g. grp2cmd2.sub221.sub221flag1")}
+}
+
+// completeConfigKeys completes the first argument with the config keys of the
+// tree, without the root section.
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	prefix := SectionPath(cmd.Root()) + "."
+	var keys []string
+	for key, f := range configKeys(cmd.Root()) {
+		keys = append(keys, strings.TrimPrefix(key, prefix)+"\t"+f.Usage)
+	}
+	sort.Strings(keys)
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// TestConfigCmd checks the config get, set and unset commands validate the
+// keys against the command tree, including the log-level key of the section of
+// each command, and edit the config file found.
+func TestConfigCmd(t *testing.T) {
+	fs := newTestFs(t, `cobravsviper:
+  # sub221 settings
+  grp2cmd2:
+    sub221:
+      sub221flag1: "from config" # comment
+`)
+	env := map[string]string{"HOME": "/home/user", "COBRAVSVIPER_CONFIG": testConfigFile}
+
+	cases := []struct {
+		args     []string
+		exitCode int
+		want     string
+	}{
+		{[]string{"config", "get", "grp2cmd2.sub221.sub221flag1"}, ExitOK, "from config\n"},
+		{[]string{"config", "set", "grp2cmd2.sub221.sub221flag1", "new"}, ExitOK, ""},
+		{[]string{"config", "set", "cobravsviper.grp2cmd2.grp2cmd2flag1", "added"}, ExitOK, ""},
+		{[]string{"config", "set", "shutdown-grace-period", "5s"}, ExitOK, ""},
+		{[]string{"config", "set", "shutdown-grace-period", "soon"}, ExitValidation, ""},
+		{[]string{"config", "set", "grp1cmd1.unknown", "x"}, ExitValidation, ""},
+		{[]string{"config", "set", "config", "other.yaml"}, ExitValidation, ""},
+		{[]string{"config", "get", "grp2cmd2.sub221.sub221flag1"}, ExitOK, "new\n"},
+		{[]string{"config", "set", "grp2cmd2.sub221.log-level", "debug"}, ExitOK, ""},
+		{[]string{"config", "set", "grp2cmd2.sub221.log-level", "loud"}, ExitValidation, ""},
+		{[]string{"config", "get", "grp2cmd2.sub221.log-level"}, ExitOK, "debug\n"},
+		{[]string{"config", "unset", "grp2cmd2.sub221.sub221flag2"}, ExitOK, ""},
+		{[]string{"config", "get", "grp2cmd2.sub221.sub221flag2"}, ExitRuntime, ""},
+	}
+
+	for _, tc := range cases {
+		var out bytes.Buffer
+		c := newCLI(Options{IOStreams: IOStreams{Out: &out, Err: &bytes.Buffer{}}, Env: env, Fs: fs})
+		result := c.execute(context.Background(), newRootCmd(c), tc.args)
+		if result.ExitCode != tc.exitCode || (tc.want != "" && out.String() != tc.want) {
+			t.Errorf("%q: got %+v and output %q, expected exit code %d and output %q", tc.args, result, out.String(), tc.exitCode, tc.want)
+		}
+	}
+
+	got, _ := afero.ReadFile(fs, testConfigFile)
+	want := `cobravsviper:
+  # sub221 settings
+  grp2cmd2:
+    sub221:
+      sub221flag1: "new" # comment
+      log-level: "debug"
+    grp2cmd2flag1: "added"
+  shutdown-grace-period: "5s"
+`
+	if string(got) != want {
+		t.Errorf("got config file\n%s\nexpected\n%s", got, want)
+	}
+}
+
+// TestConfigCmd_CreateFile checks config set creates the default config file
+// when none is found, and config unset does not.
+func TestConfigCmd_CreateFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	env := map[string]string{"HOME": "/home/user"}
+
+	c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: env, Fs: fs})
+	if result := c.execute(context.Background(), newRootCmd(c), []string{"config", "unset", "log-level"}); result.ExitCode != ExitConfigNotFound {
+		t.Errorf("config unset without config file: got %+v, expected exit code %d", result, ExitConfigNotFound)
+	}
+
+	c = newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: env, Fs: fs})
+	if result := c.execute(context.Background(), newRootCmd(c), []string{"config", "set", "log-level", "debug"}); result.ExitCode != ExitOK {
+		t.Fatalf("config set without config file: got %+v, expected exit code %d", result, ExitOK)
+	}
+	got, err := afero.ReadFile(fs, "/home/user/.config/cobravsviper/cobravsviper.conf.yaml")
+	if err != nil || !strings.Contains(string(got), "cobravsviper:\n  log-level: \"debug\"\n") {
+		t.Errorf("got config file %q, %v", got, err)
+	}
+}
+
+// TestConfigCmd_CompleteKeys checks the keys of config get, set and unset are
+// completed with the flags and the log-level key of the commands.
+func TestConfigCmd_CompleteKeys(t *testing.T) {
+	for _, command := range []string{"get", "set", "unset"} {
+		var out bytes.Buffer
+		c := newCLI(Options{IOStreams: IOStreams{Out: &out, Err: &bytes.Buffer{}}, Env: map[string]string{"HOME": "/home/user"}, Fs: afero.NewMemMapFs()})
+		result := c.execute(context.Background(), newRootCmd(c), []string{"__complete", "config", command, "grp2cmd2."})
+		if result.ExitCode != ExitOK {
+			t.Fatalf("config %s completion: got %+v", command, result)
+		}
+		for _, want := range []string{"\ngrp2cmd2.sub221.sub221flag1\t", "\ngrp2cmd2.sub221.log-level\tLog level of the grp2cmd2.sub221 command", "\ngrp2cmd2.log-level\t"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("config %s completion: missing %q in %q", command, want, out.String())
+			}
+		}
+		if strings.Contains(out.String(), "__complete.log-level") {
+			t.Errorf("config %s completion: hidden command key in %q", command, out.String())
+		}
+	}
+}
@@ -0,0 +1,396 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// convertFormats are the config file formats of the config convert command.
+var convertFormats = []string{"yaml", "toml", "json", "hcl"}
+
+// LossyItem is a value of a converted config file that the target format
+// cannot represent as is.
+type LossyItem struct {
+	// Key is the key path of the value, empty for the whole file.
+	Key string
+	// Reason tells what is lost.
+	Reason string
+}
+
+func (item LossyItem) String() string {
+	if item.Key == "" {
+		return item.Reason
+	}
+	return item.Key + ": " + item.Reason
+}
+
+// detectConfigFormat returns the format of the config file from its
+// extension, or from its content if the extension is unknown.
+func detectConfigFormat(file string, content []byte) (string, error) {
+	switch ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file), ".")); ext {
+	case "yaml", "yml":
+		return "yaml", nil
+	case "toml", "json", "hcl":
+		return ext, nil
+	case "tfvars":
+		return "hcl", nil
+	}
+	// JSON first: a JSON document is also a YAML document
+	for _, format := range []string{"json", "toml", "hcl", "yaml"} {
+		if _, err := decodeConfigE(format, content); err == nil {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("cannot detect the format of %s, expected one of %s", file, strings.Join(convertFormats, ", "))
+}
+
+// decodeConfigE decodes the config file content of the given format into
+// nested maps. The integers are decoded as int64 and the floats as float64.
+func decodeConfigE(format string, content []byte) (map[string]any, error) {
+	settings := map[string]any{}
+	switch format {
+	case "yaml":
+		if err := yaml.Unmarshal(content, &settings); err != nil {
+			return nil, err
+		}
+	case "toml":
+		if err := toml.Unmarshal(content, &settings); err != nil {
+			return nil, err
+		}
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		if err := dec.Decode(&settings); err != nil {
+			return nil, err
+		}
+	case "hcl":
+		if err := hcl.Unmarshal(content, &settings); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q, expected one of %s", format, strings.Join(convertFormats, ", "))
+	}
+	if settings == nil {
+		settings = map[string]any{}
+	}
+	return normalizeConfig(settings, format), nil
+}
+
+// normalizeConfig returns value with the types of the decoders of each format
+// unified: the maps as map[string]any, the lists as []any, the integers as
+// int64 and the floats as float64. The HCL blocks, decoded as lists of maps,
+// become maps.
+func normalizeConfig(value any, format string) map[string]any {
+	m, _ := normalizeValue(value, format).(map[string]any)
+	return m
+}
+
+func normalizeValue(value any, format string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = normalizeValue(item, format)
+		}
+		return out
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[fmt.Sprint(key)] = normalizeValue(item, format)
+		}
+		return out
+	case []map[string]any:
+		if format == "hcl" {
+			// Repeated HCL blocks are merged
+			merged := map[string]any{}
+			for _, block := range v {
+				for key, item := range normalizeValue(block, format).(map[string]any) {
+					merged[key] = item
+				}
+			}
+			return merged
+		}
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = normalizeValue(item, format)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = normalizeValue(item, format)
+		}
+		return out
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	case float32:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+// configComments reports whether the config file content of the given format
+// holds comments, which are not converted.
+func configComments(format string, content []byte) bool {
+	switch format {
+	case "yaml":
+		var doc yaml.Node
+		if yaml.Unmarshal(content, &doc) != nil {
+			return false
+		}
+		var walk func(n *yaml.Node) bool
+		walk = func(n *yaml.Node) bool {
+			if n.HeadComment != "" || n.LineComment != "" || n.FootComment != "" {
+				return true
+			}
+			for _, child := range n.Content {
+				if walk(child) {
+					return true
+				}
+			}
+			return false
+		}
+		return walk(&doc)
+	case "toml":
+		doc, err := parseTOML(content)
+		if err != nil {
+			return false
+		}
+		for _, line := range doc.lines {
+			if strings.HasPrefix(strings.TrimSpace(line), "#") {
+				return true
+			}
+		}
+		for _, e := range doc.entries {
+			if strings.Contains(doc.lines[e.endLine][e.endCol:], "#") {
+				return true
+			}
+		}
+		return false
+	case "hcl":
+		return hclComment.Match(content)
+	default:
+		return false
+	}
+}
+
+// hclComment matches the HCL comments, roughly.
+var hclComment = regexp.MustCompile(`(?m)(^|\s)(#|//|/\*)`)
+
+// convertConfigE converts the config file content from the format from to the
+// format to, and returns the values the format to cannot represent as is:
+// they are converted to the closest type, e.g. a date to a string, or
+// dropped, e.g. a null value in TOML.
+func convertConfigE(from, to string, content []byte) ([]byte, []LossyItem, error) {
+	settings, err := decodeConfigE(from, content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode the %s config: %w", from, err)
+	}
+
+	var lossy []LossyItem
+	if configComments(from, content) {
+		lossy = append(lossy, LossyItem{Reason: "the comments are not converted"})
+	}
+	prepared, _ := prepare(to, "", settings, &lossy)
+	settings = prepared.(map[string]any)
+
+	var out []byte
+	switch to {
+	case "yaml":
+		var b bytes.Buffer
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		err = enc.Encode(settings)
+		out = b.Bytes()
+	case "toml":
+		var b bytes.Buffer
+		enc := toml.NewEncoder(&b)
+		enc.SetIndentTables(true)
+		err = enc.Encode(settings)
+		out = b.Bytes()
+	case "json":
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(settings)
+		out = b.Bytes()
+	case "hcl":
+		var b strings.Builder
+		encodeHCLBody(&b, settings, "")
+		out = []byte(b.String())
+	default:
+		return nil, nil, fmt.Errorf("unsupported config format %q, expected one of %s", to, strings.Join(convertFormats, ", "))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode the %s config: %w", to, err)
+	}
+	return out, lossy, nil
+}
+
+// prepare returns the value of key converted to the types of the format to,
+// or false if it cannot be represented at all. The changes are appended to
+// lossy.
+func prepare(to, key string, value any, lossy *[]LossyItem) (any, bool) {
+	report := func(reason string) {
+		*lossy = append(*lossy, LossyItem{Key: key, Reason: reason})
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for _, k := range sortedKeys(v) {
+			childKey := k
+			if key != "" {
+				childKey = key + "." + k
+			}
+			if item, ok := prepare(to, childKey, v[k], lossy); ok {
+				out[k] = item
+			}
+		}
+		return out, true
+	case []any:
+		out := make([]any, 0, len(v))
+		for i, item := range v {
+			if item, ok := prepare(to, fmt.Sprintf("%s[%d]", key, i), item, lossy); ok {
+				out = append(out, item)
+			}
+		}
+		return out, true
+	case nil:
+		if to == "toml" || to == "hcl" {
+			report("null value dropped")
+			return nil, false
+		}
+	case float64:
+		if (math.IsNaN(v) || math.IsInf(v, 0)) && (to == "json" || to == "hcl") {
+			report(fmt.Sprintf("%v value dropped", v))
+			return nil, false
+		}
+	case time.Time:
+		if to == "json" || to == "hcl" {
+			report("date converted to a string")
+			return v.Format(time.RFC3339Nano), true
+		}
+	case toml.LocalDate, toml.LocalTime, toml.LocalDateTime:
+		if to != "toml" {
+			report("local date or time converted to a string")
+			return fmt.Sprint(v), true
+		}
+	}
+	return value, true
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hclIdentifier matches the keys written unquoted in HCL.
+var hclIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// hclKey formats key as an HCL key.
+func hclKey(key string) string {
+	if hclIdentifier.MatchString(key) {
+		return key
+	}
+	return jsonString(key)
+}
+
+// encodeHCLBody writes the attributes of settings to b, then its sections as
+// blocks, each key in order.
+func encodeHCLBody(b *strings.Builder, settings map[string]any, indent string) {
+	keys := sortedKeys(settings)
+	for _, key := range keys {
+		if _, ok := settings[key].(map[string]any); !ok {
+			fmt.Fprintf(b, "%s%s = %s\n", indent, hclKey(key), hclValue(settings[key], indent))
+		}
+	}
+	for _, key := range keys {
+		if section, ok := settings[key].(map[string]any); ok {
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(b, "%s%s {\n", indent, hclKey(key))
+			encodeHCLBody(b, section, indent+"  ")
+			fmt.Fprintf(b, "%s}\n", indent)
+		}
+	}
+}
+
+// hclValue formats value as an HCL value.
+func hclValue(value any, indent string) string {
+	switch v := value.(type) {
+	case string:
+		return jsonString(v)
+	case float64:
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = hclValue(item, indent)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		var b strings.Builder
+		b.WriteString("{\n")
+		encodeHCLBody(&b, v, indent+"  ")
+		b.WriteString(indent + "}")
+		return b.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testConvertSettings is the content of testConvertFiles, decoded.
+var testConvertSettings = map[string]any{
+	"cobravsviper": map[string]any{
+		"log-level": "debug",
+		"grp2cmd2": map[string]any{
+			"sub221": map[string]any{
+				"sub221flag1": "value",
+				"count":       int64(3),
+				"ratio":       1.5,
+				"enabled":     true,
+				"list":        []any{"a", "b"},
+			},
+		},
+	},
+}
+
+// testConvertFiles holds the same settings in each format.
+var testConvertFiles = map[string]string{
+	"yaml": `cobravsviper:
+  log-level: debug
+  grp2cmd2:
+    sub221:
+      sub221flag1: value
+      count: 3
+      ratio: 1.5
+      enabled: true
+      list: [a, b]
+`,
+	"toml": `[cobravsviper]
+log-level = "debug"
+
+[cobravsviper.grp2cmd2.sub221]
+sub221flag1 = "value"
+count = 3
+ratio = 1.5
+enabled = true
+list = ["a", "b"]
+`,
+	"json": `{"cobravsviper": {"log-level": "debug", "grp2cmd2": {"sub221": {
+  "sub221flag1": "value", "count": 3, "ratio": 1.5, "enabled": true, "list": ["a", "b"]}}}}`,
+	"hcl": `cobravsviper {
+  log-level = "debug"
+  grp2cmd2 {
+    sub221 {
+      sub221flag1 = "value"
+      count = 3
+      ratio = 1.5
+      enabled = true
+      list = ["a", "b"]
+    }
+  }
+}
+`,
+}
+
+// TestConvertConfigE checks the conversions between all the formats preserve
+// the nested sections and the types of the values, without lossy item.
+func TestConvertConfigE(t *testing.T) {
+	for from, content := range testConvertFiles {
+		for _, to := range convertFormats {
+			out, lossy, err := convertConfigE(from, to, []byte(content))
+			if err != nil {
+				t.Errorf("%s to %s: %v", from, to, err)
+				continue
+			}
+			if len(lossy) > 0 {
+				t.Errorf("%s to %s: unexpected lossy items %v", from, to, lossy)
+			}
+			got, err := decodeConfigE(to, out)
+			if err != nil {
+				t.Errorf("%s to %s: the output does not decode: %v\n%s", from, to, err, out)
+				continue
+			}
+			if !reflect.DeepEqual(got, testConvertSettings) {
+				t.Errorf("%s to %s: got %#v, expected %#v\n%s", from, to, got, testConvertSettings, out)
+			}
+		}
+	}
+}
+
+// TestConvertConfigE_Lossy checks the values that cannot be represented in the
+// target format are reported.
+func TestConvertConfigE_Lossy(t *testing.T) {
+	cases := []struct {
+		from, to string
+		content  string
+		want     []string
+	}{
+		{"yaml", "toml", "a:\n  b: null # comment\n  c: 1\n", []string{"the comments are not converted", "a.b: null value dropped"}},
+		{"json", "hcl", `{"a": {"l": [1, null]}}`, []string{"a.l[1]: null value dropped"}},
+		{"toml", "json", "a = 2025-01-02T03:04:05Z\nb = 2025-01-02\n", []string{"a: date converted to a string", "b: local date or time converted to a string"}},
+		{"toml", "json", "a = nan\n", []string{"a: NaN value dropped"}},
+		{"toml", "yaml", "a = nan\n", nil},
+		{"json", "toml", `{"a": null}`, []string{"a: null value dropped"}},
+	}
+
+	for _, c := range cases {
+		_, lossy, err := convertConfigE(c.from, c.to, []byte(c.content))
+		if err != nil {
+			t.Errorf("%s to %s of %q: %v", c.from, c.to, c.content, err)
+			continue
+		}
+		var got []string
+		for _, item := range lossy {
+			got = append(got, item.String())
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s to %s of %q: got lossy items %q, expected %q", c.from, c.to, c.content, got, c.want)
+		}
+	}
+}
+
+// TestDetectConfigFormat checks the format is detected from the extension,
+// then from the content.
+func TestDetectConfigFormat(t *testing.T) {
+	cases := []struct {
+		file    string
+		content string
+		want    string
+	}{
+		{"a.yml", "", "yaml"},
+		{"a.tfvars", "", "hcl"},
+		{"a.conf", testConvertFiles["json"], "json"},
+		{"a.conf", testConvertFiles["toml"], "toml"},
+		{"a.conf", testConvertFiles["hcl"], "hcl"},
+		{"a.conf", testConvertFiles["yaml"], "yaml"},
+	}
+
+	for _, c := range cases {
+		got, err := detectConfigFormat(c.file, []byte(c.content))
+		if err != nil || got != c.want {
+			t.Errorf("detectConfigFormat(%q, %q) = %q, %v, expected %q", c.file, strings.SplitN(c.content, "\n", 2)[0], got, err, c.want)
+		}
+	}
+}
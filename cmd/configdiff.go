@@ -0,0 +1,209 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/nicop311/cobravsviper/pkg/output"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// effectiveConfig is the label of the effective config in a config diff.
+const effectiveConfig = "effective"
+
+// ResolvedValue is the value of a config key resolved through the config
+// layers, with the layer it comes from.
+type ResolvedValue struct {
+	Value  any    `json:"value"`
+	Source Source `json:"source"`
+}
+
+// ConfigChange is a key of the config resolved differently from two config
+// files.
+type ConfigChange struct {
+	Key string        `json:"key"`
+	A   ResolvedValue `json:"a"`
+	B   ResolvedValue `json:"b"`
+}
+
+// ConfigDiff is the difference between the config resolved from two config
+// files.
+type ConfigDiff struct {
+	A       string         `json:"a"`
+	B       string         `json:"b"`
+	Command string         `json:"command,omitempty"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// resolveConfigE resolves the config keys of the command named by the words
+// of command, or of the whole tree if command is empty, through the env
+// variables, the dotenv files, the config file and the defaults, like the
+// command would without flag on its command line.
+//
+// The config file is file, or the config file found if file is empty. The
+// dotenv files are the ones given to rootCmd.
+func (c *cli) resolveConfigE(rootCmd *cobra.Command, file string, command []string) (map[string]ResolvedValue, error) {
+	child := newCLI(Options{IOStreams: IOStreams{In: c.opts.In, Out: io.Discard, Err: io.Discard}, Env: c.opts.Env, Fs: c.opts.Fs})
+	childRoot := newRootCmd(child)
+
+	var errs []error
+	rootCmd.PersistentFlags().Visit(func(f *pflag.Flag) {
+		if !isConfigLocationFlag(f.Name) || (f.Name == "config" && file != "") {
+			return
+		}
+		values := []string{f.Value.String()}
+		if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+			values = sliceValue.GetSlice()
+		}
+		for _, value := range values {
+			errs = append(errs, childRoot.PersistentFlags().Set(f.Name, value))
+		}
+	})
+	if file != "" {
+		errs = append(errs, childRoot.PersistentFlags().Set("config", file))
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := child.loadConfigE(childRoot); err != nil {
+		return nil, err
+	}
+
+	var keys map[string]*pflag.Flag
+	if len(command) == 0 {
+		keys = configKeys(childRoot)
+		var walk func(cmd *cobra.Command) error
+		walk = func(cmd *cobra.Command) error {
+			if !skipConfig(cmd) {
+				if err := child.InitViperSubCmdE(cmd, nil); err != nil {
+					return err
+				}
+			}
+			for _, sub := range cmd.Commands() {
+				if err := walk(sub); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err := walk(childRoot); err != nil {
+			return nil, err
+		}
+	} else {
+		target, args, err := childRoot.Find(command)
+		if err != nil || len(args) > 0 {
+			return nil, &UsageError{Err: fmt.Errorf("unknown command %q", strings.Join(command, " "))}
+		}
+		keys = map[string]*pflag.Flag{}
+		for _, cmd := range commandPath(target) {
+			if skipConfig(cmd) {
+				continue
+			}
+			if err := child.InitViperSubCmdE(cmd, nil); err != nil {
+				return nil, err
+			}
+		}
+		target.Flags().VisitAll(func(f *pflag.Flag) {
+			owner := flagOwner(target, f.Name)
+			if f.Name == "help" || (owner == childRoot && isConfigLocationFlag(f.Name)) || skipConfig(owner) {
+				return
+			}
+			keys[SectionPath(owner)+"."+f.Name] = f
+		})
+	}
+
+	resolved := make(map[string]ResolvedValue, len(keys))
+	for key, f := range keys {
+		if logLevel, ok := f.Value.(*logLevelValue); ok {
+			value, source, _ := child.commandLogLevel(logLevel.cmd)
+			resolved[key] = ResolvedValue{Value: value, Source: source}
+			continue
+		}
+		resolved[key] = ResolvedValue{Value: child.v.Get(key), Source: child.sources[key]}
+	}
+	return resolved, nil
+}
+
+// diffConfig returns the keys resolved differently in a and b, in order. The
+// values are compared as they are given to their flag, so 1 in YAML equals 1
+// in TOML.
+func diffConfig(a, b map[string]ResolvedValue) []ConfigChange {
+	keys := make([]string, 0, len(a))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []ConfigChange{}
+	for _, key := range keys {
+		if envValue(a[key].Value) != envValue(b[key].Value) {
+			changes = append(changes, ConfigChange{Key: key, A: a[key], B: b[key]})
+		}
+	}
+	return changes
+}
+
+// Table returns the changes of diff, one per row, in the table format.
+func (diff ConfigDiff) Table() (header []string, rows [][]string) {
+	for _, change := range diff.Changes {
+		rows = append(rows, []string{
+			change.Key,
+			fmt.Sprintf("%s (%s)", envValue(change.A.Value), change.A.Source),
+			fmt.Sprintf("%s (%s)", envValue(change.B.Value), change.B.Source),
+		})
+	}
+	return []string{"key", diff.A, diff.B}, rows
+}
+
+// writeConfigDiffE writes diff to w in the given format: unified, like diff
+// -u with one line per key, or one of the output formats (see renderE).
+func writeConfigDiffE(w io.Writer, diff ConfigDiff, format string, opts output.Options) error {
+	if format != "unified" {
+		p, err := output.NewPrinter(format, opts)
+		if err != nil {
+			return &UsageError{Err: fmt.Errorf("unknown diff format %q, expected unified or an output format: %w", format, err)}
+		}
+		return p.Print(w, diff)
+	}
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", diff.A, diff.B)
+	if diff.Command != "" {
+		fmt.Fprintf(w, "@@ %s @@\n", diff.Command)
+	}
+	for _, change := range diff.Changes {
+		fmt.Fprintf(w, "-%s = %s (%s)\n", change.Key, jsonString(envValue(change.A.Value)), change.A.Source)
+		fmt.Fprintf(w, "+%s = %s (%s)\n", change.Key, jsonString(envValue(change.B.Value)), change.B.Source)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/nicop311/cobravsviper/pkg/output"
+	"github.com/spf13/afero"
+)
+
+// TestConfigDiff checks config diff compares the config resolved from two
+// files of different formats, or from the effective config and a file.
+func TestConfigDiff(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/a.yaml": `cobravsviper:
+  log-level: info
+  rootpersistentflag1: same
+  grp2cmd2:
+    sub221:
+      sub221flag1: a
+      sub221flag2: hidden by env
+`,
+		"/b.toml": `[cobravsviper]
+log-level = "info"
+rootpersistentflag1 = "same"
+rootflag1 = "only in b"
+
+[cobravsviper.grp2cmd2.sub221]
+sub221flag1 = "b"
+sub221flag2 = "also hidden by env"
+`,
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := map[string]string{
+		"HOME":                "/home/user",
+		"COBRAVSVIPER_CONFIG": "/a.yaml",
+		"COBRAVSVIPER_GRP2CMD2_SUB221_SUB221FLAG2": "from env",
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		exitCode int
+		want     []string
+	}{
+		{"two files", []string{"/a.yaml", "/b.toml"}, ExitOK, []string{
+			"cobravsviper.grp2cmd2.sub221.sub221flag1",
+			"cobravsviper.rootflag1",
+		}},
+		{"command", []string{"/a.yaml", "/b.toml", "--command", "grp2cmd2 sub221"}, ExitOK, []string{
+			"cobravsviper.grp2cmd2.sub221.sub221flag1",
+		}},
+		{"effective config", []string{"/b.toml", "--command", "grp2cmd2.sub221"}, ExitOK, []string{
+			"cobravsviper.grp2cmd2.sub221.sub221flag1",
+		}},
+		{"unknown command", []string{"/a.yaml", "/b.toml", "--command", "nope"}, ExitUsage, nil},
+		{"missing file", []string{"/c.yaml"}, ExitConfigNotFound, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			c := newCLI(Options{IOStreams: IOStreams{Out: &out, Err: &bytes.Buffer{}}, Env: env, Fs: fs})
+			args := append([]string{"config", "diff", "-o", "json"}, tc.args...)
+			result := c.execute(context.Background(), newRootCmd(c), args)
+			if result.ExitCode != tc.exitCode {
+				t.Fatalf("got %+v, expected exit code %d", result, tc.exitCode)
+			}
+			if tc.exitCode != ExitOK {
+				return
+			}
+
+			var diff ConfigDiff
+			if err := json.Unmarshal(out.Bytes(), &diff); err != nil {
+				t.Fatalf("invalid JSON diff %q: %v", out.String(), err)
+			}
+			var got []string
+			for _, change := range diff.Changes {
+				got = append(got, change.Key)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got changed keys %q, expected %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestWriteConfigDiffE checks the unified diff output.
+func TestWriteConfigDiffE(t *testing.T) {
+	diff := ConfigDiff{A: "a.yaml", B: "b.toml", Command: "grp2cmd2 sub221", Changes: []ConfigChange{{
+		Key: "cobravsviper.log-level",
+		A:   ResolvedValue{Value: "debug", Source: SourceConfig},
+		B:   ResolvedValue{Value: "info", Source: SourceDefault},
+	}}}
+	want := `--- a.yaml
++++ b.toml
+@@ grp2cmd2 sub221 @@
+-cobravsviper.log-level = "debug" (config)
++cobravsviper.log-level = "info" (default)
+`
+	var out bytes.Buffer
+	if err := writeConfigDiffE(&out, diff, "unified", output.Options{}); err != nil || out.String() != want {
+		t.Errorf("writeConfigDiffE() = %q, %v, expected %q", out.String(), err, want)
+	}
+}
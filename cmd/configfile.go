@@ -0,0 +1,1058 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// The config file edits below change the text of the file in place instead of
+// re-marshalling it, so the comments, the key order, the indentation and the
+// quoting of the untouched values are preserved. They support the sections
+// written as YAML block mappings and TOML tables; the constructs below return
+// an error wrapping errUnsupportedConfig:
+//   - YAML: multi-document files, and sections written as flow mappings or
+//     aliases
+//   - TOML: arrays of tables, and sections written as inline tables or dotted
+//     keys
+//
+// The JSON files are decoded and encoded back with the same key order and
+// indentation, the lists on a single line.
+
+// errUnsupportedConfig is wrapped by the errors of the config file constructs
+// the edits do not support.
+var errUnsupportedConfig = errors.New("not supported by config set and unset, edit the config file by hand")
+
+// configFormat returns the format of a config file from its extension: yaml,
+// toml or json.
+func configFormat(file string) (string, error) {
+	switch ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file), ".")); ext {
+	case "yaml", "yml":
+		return "yaml", nil
+	case "toml", "json":
+		return ext, nil
+	default:
+		return "", fmt.Errorf("unsupported config file format %q, expected yaml, toml or json", ext)
+	}
+}
+
+// setConfigValueE returns content, a config file of the given format, with the
+// key path set to value. The missing sections are created.
+func setConfigValueE(format string, content []byte, path []string, value any) ([]byte, error) {
+	var out []byte
+	var err error
+	switch format {
+	case "yaml":
+		out, err = setYAMLValueE(content, path, value)
+	case "toml":
+		out, err = setTOMLValueE(content, path, value)
+	case "json":
+		out, err = setJSONValueE(content, path, value)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, checkConfigE(format, out)
+}
+
+// unsetConfigValueE returns content, a config file of the given format,
+// without the key path, and whether the key was found. The sections left empty
+// are removed as well.
+func unsetConfigValueE(format string, content []byte, path []string) ([]byte, bool, error) {
+	var out []byte
+	var found bool
+	var err error
+	switch format {
+	case "yaml":
+		out, found, err = unsetYAMLValueE(content, path)
+	case "toml":
+		out, found, err = unsetTOMLValueE(content, path)
+	case "json":
+		out, found, err = unsetJSONValueE(content, path)
+	default:
+		return nil, false, fmt.Errorf("unsupported config file format %q", format)
+	}
+	if err != nil || !found {
+		return content, found, err
+	}
+	return out, true, checkConfigE(format, out)
+}
+
+// checkConfigE checks an edited config file is still valid, e.g. a new TOML
+// table does not redefine a dotted key.
+func checkConfigE(format string, content []byte) error {
+	var v map[string]any
+	var err error
+	switch format {
+	case "yaml":
+		err = yaml.Unmarshal(content, &v)
+	case "toml":
+		err = toml.Unmarshal(content, &v)
+	case "json":
+		err = json.Unmarshal(content, &v)
+	}
+	if err != nil {
+		return fmt.Errorf("the edited config file would be invalid: %w", err)
+	}
+	return nil
+}
+
+// splitLines splits s into lines, each keeping its line ending.
+func splitLines(s []byte) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.SplitAfter(string(s), "\n")
+}
+
+// replaceLines replaces the lines [from, to] of lines with repl.
+func replaceLines(lines []string, from, to int, repl ...string) []byte {
+	var b strings.Builder
+	for _, line := range lines[:from] {
+		b.WriteString(line)
+	}
+	for _, line := range repl {
+		b.WriteString(line)
+	}
+	for _, line := range lines[to+1:] {
+		b.WriteString(line)
+	}
+	return []byte(b.String())
+}
+
+// insertLines inserts repl after the line index after, -1 to insert them
+// first.
+func insertLines(lines []string, after int, repl ...string) []byte {
+	if after >= 0 {
+		lines[after] = withNewline(lines[after])
+	}
+	return replaceLines(lines, after+1, after, repl...)
+}
+
+// withNewline ends line with a new line if needed.
+func withNewline(line string) string {
+	if strings.HasSuffix(line, "\n") {
+		return line
+	}
+	return line + "\n"
+}
+
+// indentOf returns the number of leading blanks of line.
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// jsonString returns s as a JSON string, which is also a valid YAML double
+// quoted and TOML basic string.
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// inlineValue formats value as an inline YAML or TOML value: the strings
+// double quoted, the lists in flow style.
+func inlineValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return jsonString(v)
+	case []string:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = jsonString(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// YAML
+
+// setYAMLValueE sets the key path of the YAML document content to value.
+func setYAMLValueE(content []byte, path []string, value any) ([]byte, error) {
+	lines := splitLines(content)
+	root, err := yamlRoot(content)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		// Empty document: append the new sections
+		return insertLines(lines, len(lines)-1, newYAMLLines(0, yamlIndentUnit(nil), path, value)...), nil
+	}
+
+	mapping := root
+	var parentKey *yaml.Node
+	for i, segment := range path {
+		key, val := yamlLookup(mapping, segment)
+		if key == nil {
+			return insertYAMLLines(lines, mapping, parentKey, yamlIndentUnit(root), path[i:], value)
+		}
+		if i == len(path)-1 {
+			return replaceYAMLValue(lines, key, val, value)
+		}
+		switch {
+		case val.Kind == yaml.MappingNode && val.Style&yaml.FlowStyle == 0:
+			mapping, parentKey = val, key
+		case val.Kind == yaml.ScalarNode && val.Tag == "!!null" && val.Value == "":
+			// Empty section, e.g. "sub221:" alone on its line
+			mapping, parentKey = &yaml.Node{Kind: yaml.MappingNode}, key
+		default:
+			return nil, yamlSectionError(path[:i+1], val)
+		}
+	}
+	return nil, errors.New("empty key")
+}
+
+// unsetYAMLValueE removes the key path from the YAML document content.
+func unsetYAMLValueE(content []byte, path []string) ([]byte, bool, error) {
+	root, err := yamlRoot(content)
+	if err != nil || root == nil {
+		return content, false, err
+	}
+	mapping := root
+	for i, segment := range path {
+		key, val := yamlLookup(mapping, segment)
+		if key == nil {
+			return content, false, nil
+		}
+		if i < len(path)-1 {
+			if val.Kind != yaml.MappingNode || val.Style&yaml.FlowStyle != 0 {
+				if val.Kind == yaml.MappingNode || val.Kind == yaml.AliasNode {
+					return content, false, yamlSectionError(path[:i+1], val)
+				}
+				return content, false, nil
+			}
+			mapping = val
+			continue
+		}
+
+		lines := splitLines(content)
+		out := replaceLines(lines, key.Line-1, yamlEntryEnd(lines, key))
+		if len(mapping.Content) == 2 && len(path) > 1 {
+			// Remove the section left empty as well
+			out, _, err = unsetYAMLValueE(out, path[:len(path)-1])
+		}
+		return out, true, err
+	}
+	return content, false, nil
+}
+
+// yamlSectionError returns the error of the value val of the section path,
+// which is not a block mapping.
+func yamlSectionError(path []string, val *yaml.Node) error {
+	key := strings.Join(path, ".")
+	switch {
+	case val.Kind == yaml.MappingNode:
+		return fmt.Errorf("%s is a flow mapping: %w", key, errUnsupportedConfig)
+	case val.Kind == yaml.AliasNode:
+		return fmt.Errorf("%s is an alias: %w", key, errUnsupportedConfig)
+	default:
+		return fmt.Errorf("%s is not a section", key)
+	}
+}
+
+// yamlRoot returns the root mapping of the YAML document content, or nil if
+// the document is empty.
+func yamlRoot(content []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	var next yaml.Node
+	if err := dec.Decode(&next); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("multi-document YAML file: %w", errUnsupportedConfig)
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		return nil, nil
+	}
+	if root.Kind != yaml.MappingNode || root.Style&yaml.FlowStyle != 0 {
+		return nil, errors.New("the YAML document is not a block mapping")
+	}
+	return root, nil
+}
+
+// yamlLookup returns the key and value nodes of key in mapping.
+func yamlLookup(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// yamlIndentUnit returns the indentation of the nested mappings of root, 2 by
+// default.
+func yamlIndentUnit(root *yaml.Node) int {
+	if root == nil {
+		return 2
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i], root.Content[i+1]
+		if val.Kind == yaml.MappingNode && len(val.Content) > 0 && val.Content[0].Column > key.Column {
+			return val.Content[0].Column - key.Column
+		}
+		if unit := yamlIndentUnit(val); val.Kind == yaml.MappingNode && unit != 2 {
+			return unit
+		}
+	}
+	return 2
+}
+
+// yamlEntryEnd returns the index of the last line of the entry of key: the
+// lines indented deeper than key, or the block sequence items at the level
+// of key. The comments and blank lines ending the entry are not part of it.
+func yamlEntryEnd(lines []string, key *yaml.Node) int {
+	end := key.Line - 1
+	col := key.Column - 1
+	for i := end + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := indentOf(lines[i])
+		if indent > col || (indent == col && (trimmed == "-" || strings.HasPrefix(trimmed, "- "))) {
+			end = i
+			continue
+		}
+		break
+	}
+	return end
+}
+
+// newYAMLLines returns the lines of the nested sections path set to value,
+// starting at indent.
+func newYAMLLines(indent, unit int, path []string, value any) []string {
+	var lines []string
+	for i, segment := range path {
+		prefix := strings.Repeat(" ", indent+i*unit) + yamlKey(segment) + ":"
+		if i == len(path)-1 {
+			prefix += " " + inlineValue(value)
+		}
+		lines = append(lines, prefix+"\n")
+	}
+	return lines
+}
+
+// yamlKey formats key as a YAML mapping key.
+func yamlKey(key string) string {
+	out, err := yaml.Marshal(key)
+	if err != nil {
+		return jsonString(key)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// insertYAMLLines inserts the nested sections path set to value in mapping,
+// after its last entry. parentKey is the key of mapping, nil for the root.
+func insertYAMLLines(lines []string, mapping, parentKey *yaml.Node, unit int, path []string, value any) ([]byte, error) {
+	var indent, after int
+	switch {
+	case len(mapping.Content) > 0:
+		first, last := mapping.Content[0], mapping.Content[len(mapping.Content)-2]
+		indent = first.Column - 1
+		after = yamlEntryEnd(lines, last)
+	case parentKey != nil:
+		indent = parentKey.Column - 1 + unit
+		after = parentKey.Line - 1
+	default:
+		after = len(lines) - 1
+	}
+	return insertLines(lines, after, newYAMLLines(indent, unit, path, value)...), nil
+}
+
+// replaceYAMLValue replaces the value val of key with value. A single line
+// scalar is replaced in place, keeping its quoting style and the comment
+// following it.
+func replaceYAMLValue(lines []string, key, val *yaml.Node, value any) ([]byte, error) {
+	keyLine := key.Line - 1
+	end := yamlEntryEnd(lines, key)
+	line := lines[keyLine]
+
+	if val.Kind == yaml.ScalarNode && val.Line == key.Line && end == keyLine &&
+		val.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		start := val.Column - 1
+		stop := yamlScalarEnd(line, start, val.Style)
+		return replaceLines(lines, keyLine, keyLine, line[:start]+yamlScalar(value, val.Style)+line[stop:]), nil
+	}
+
+	// Multi-line value: replace the whole entry
+	colon := yamlKeyEnd(line, key)
+	if colon < 0 {
+		return nil, fmt.Errorf("cannot find the value of %s at line %d", key.Value, key.Line)
+	}
+	return replaceLines(lines, keyLine, end, line[:colon+1]+" "+inlineValue(value)+"\n"), nil
+}
+
+// yamlKeyEnd returns the index of the colon following key in line.
+func yamlKeyEnd(line string, key *yaml.Node) int {
+	start := key.Column - 1
+	if key.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		start = yamlScalarEnd(line, start, key.Style)
+	} else {
+		start += len(key.Value)
+	}
+	if start > len(line) {
+		return -1
+	}
+	i := strings.IndexByte(line[start:], ':')
+	if i < 0 {
+		return -1
+	}
+	return start + i
+}
+
+// yamlScalarEnd returns the index ending the single line scalar of the given
+// style starting at line[start].
+func yamlScalarEnd(line string, start int, style yaml.Style) int {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\\' {
+				i++
+			} else if line[i] == '"' {
+				return i + 1
+			}
+		}
+	case style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(line); i++ {
+			if line[i] == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+	default:
+		end := strings.TrimRight(line, "\r\n")
+		if i := strings.Index(end[start:], " #"); i >= 0 {
+			end = end[:start+i]
+		}
+		return len(strings.TrimRight(end, " \t"))
+	}
+	return len(strings.TrimRight(line, "\r\n"))
+}
+
+// yamlScalar formats value as a YAML scalar, keeping the quoting style of the
+// replaced string.
+func yamlScalar(value any, style yaml.Style) string {
+	s, ok := value.(string)
+	switch {
+	case !ok:
+		return inlineValue(value)
+	case style&yaml.SingleQuotedStyle != 0:
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	case style&yaml.DoubleQuotedStyle != 0:
+		return jsonString(s)
+	default:
+		return yamlKey(s)
+	}
+}
+
+// TOML
+
+// tomlEntry is a key/value pair of a TOML document.
+type tomlEntry struct {
+	path       []string
+	line       int // line of the key
+	valueLine  int // line and column where the value starts
+	valueCol   int
+	endLine    int // line and column where the value ends
+	endCol     int
+	tableIndex int // index of the table the entry belongs to
+}
+
+// tomlTable is a table header of a TOML document.
+type tomlTable struct {
+	path []string
+	line int
+}
+
+// tomlDocument is the key/value pairs and tables of a TOML document, in
+// document order. The entries before the first table belong to the table -1.
+type tomlDocument struct {
+	lines   []string
+	tables  []tomlTable
+	entries []tomlEntry
+}
+
+// parseTOML parses the key/value pairs and table headers of content. The
+// arrays of tables are not supported.
+func parseTOML(content []byte) (*tomlDocument, error) {
+	doc := &tomlDocument{lines: splitLines(content)}
+	table := -1
+	var tablePath []string
+	for i := 0; i < len(doc.lines); i++ {
+		line := doc.lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.HasPrefix(trimmed, "[["):
+			return nil, fmt.Errorf("line %d: array of tables: %w", i+1, errUnsupportedConfig)
+		case strings.HasPrefix(trimmed, "["):
+			path, rest, err := parseTOMLKey(trimmed[1:])
+			if err != nil || !strings.HasPrefix(strings.TrimSpace(rest), "]") {
+				return nil, fmt.Errorf("line %d: invalid table header", i+1)
+			}
+			tablePath = path
+			doc.tables = append(doc.tables, tomlTable{path: path, line: i})
+			table = len(doc.tables) - 1
+		default:
+			key, rest, err := parseTOMLKey(line)
+			if err != nil || !strings.HasPrefix(strings.TrimLeft(rest, " \t"), "=") {
+				return nil, fmt.Errorf("line %d: invalid key/value pair", i+1)
+			}
+			col := len(line) - len(rest)
+			col += strings.IndexByte(rest, '=') + 1
+			for col < len(line) && (line[col] == ' ' || line[col] == '\t') {
+				col++
+			}
+			endLine, endCol := tomlValueEnd(doc.lines, i, col)
+			doc.entries = append(doc.entries, tomlEntry{
+				path:       append(append([]string{}, tablePath...), key...),
+				line:       i,
+				valueLine:  i,
+				valueCol:   col,
+				endLine:    endLine,
+				endCol:     endCol,
+				tableIndex: table,
+			})
+			i = endLine
+		}
+	}
+	return doc, nil
+}
+
+// tomlBareKey matches the TOML bare keys.
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// parseTOMLKey parses the dotted key starting s, and returns its segments and
+// the rest of s.
+func parseTOMLKey(s string) ([]string, string, error) {
+	var path []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case strings.HasPrefix(s, `"`):
+			end := yamlScalarEnd(s, 0, yaml.DoubleQuotedStyle)
+			var segment string
+			if err := json.Unmarshal([]byte(s[:end]), &segment); err != nil {
+				return nil, s, err
+			}
+			path, s = append(path, segment), s[end:]
+		case strings.HasPrefix(s, "'"):
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return nil, s, errors.New("unterminated key")
+			}
+			path, s = append(path, s[1:end+1]), s[end+2:]
+		default:
+			bare := tomlBareKey.FindString(s)
+			if bare == "" {
+				return nil, s, errors.New("invalid key")
+			}
+			path, s = append(path, bare), s[len(bare):]
+		}
+		rest := strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(rest, ".") {
+			return path, s, nil
+		}
+		s = rest[1:]
+	}
+}
+
+// tomlValueEnd returns the line and the column ending the value starting at
+// lines[line][col]: the strings, arrays and inline tables may span several
+// lines.
+func tomlValueEnd(lines []string, line, col int) (int, int) {
+	depth := 0
+	for ; line < len(lines); line, col = line+1, 0 {
+		s := lines[line]
+		for col < len(s) {
+			switch rest := s[col:]; {
+			case strings.HasPrefix(rest, `"""`), strings.HasPrefix(rest, "'''"):
+				delim := rest[:3]
+				l, c := line, col+3
+				for l < len(lines) {
+					if i := strings.Index(lines[l][c:], delim); i >= 0 {
+						c += i + 3
+						break
+					}
+					l, c = l+1, 0
+				}
+				if l >= len(lines) {
+					return len(lines) - 1, len(lines[len(lines)-1])
+				}
+				line, col, s = l, c, lines[l]
+				if depth == 0 {
+					return line, col
+				}
+			case rest[0] == '"':
+				col = yamlScalarEnd(s, col, yaml.DoubleQuotedStyle)
+				if depth == 0 {
+					return line, col
+				}
+			case rest[0] == '\'':
+				col = yamlScalarEnd(s, col, yaml.SingleQuotedStyle)
+				if depth == 0 {
+					return line, col
+				}
+			case rest[0] == '[' || rest[0] == '{':
+				depth++
+				col++
+			case rest[0] == ']' || rest[0] == '}':
+				depth--
+				col++
+				if depth == 0 {
+					return line, col
+				}
+			case rest[0] == '#' || rest[0] == '\n' || rest[0] == '\r':
+				if depth == 0 {
+					return line, len(strings.TrimRight(s[:col], " \t"))
+				}
+				col = len(s)
+			default:
+				col++
+			}
+		}
+		if depth == 0 {
+			return line, len(strings.TrimRight(s, " \t\r\n"))
+		}
+	}
+	return len(lines) - 1, len(lines[len(lines)-1])
+}
+
+// lookup returns the entry of the key path, or nil.
+func (doc *tomlDocument) lookup(path []string) *tomlEntry {
+	for i := range doc.entries {
+		if equalPath(doc.entries[i].path, path) {
+			return &doc.entries[i]
+		}
+	}
+	return nil
+}
+
+// sectionErrorE returns an error if a section of the key path is a value of
+// the document: an inline table, a dotted key or a value of another type.
+func (doc *tomlDocument) sectionErrorE(path []string) error {
+	for _, e := range doc.entries {
+		if len(e.path) >= len(path) || !equalPath(e.path, path[:len(e.path)]) {
+			continue
+		}
+		key := strings.Join(e.path, ".")
+		if strings.HasPrefix(doc.lines[e.valueLine][e.valueCol:], "{") {
+			return fmt.Errorf("%s is an inline table: %w", key, errUnsupportedConfig)
+		}
+		return fmt.Errorf("%s is not a section", key)
+	}
+	return nil
+}
+
+// dottedSection reports whether the section path is defined by the dotted
+// keys of an entry, e.g. "c.d = 1" in the table "[a]" for the section a.c,
+// and not by a table.
+func (doc *tomlDocument) dottedSection(path []string) bool {
+	for _, table := range doc.tables {
+		if equalPath(table.path, path) {
+			return false
+		}
+	}
+	for _, e := range doc.entries {
+		tableLen := 0
+		if e.tableIndex >= 0 {
+			tableLen = len(doc.tables[e.tableIndex].path)
+		}
+		if tableLen < len(path) && len(e.path) > len(path) && equalPath(e.path[:len(path)], path) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalPath reports whether the key paths a and b are equal.
+func equalPath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlKey formats a key path as a TOML dotted key.
+func tomlKey(path []string) string {
+	segments := make([]string, len(path))
+	for i, segment := range path {
+		if tomlBareKey.FindString(segment) == segment && segment != "" {
+			segments[i] = segment
+		} else {
+			segments[i] = jsonString(segment)
+		}
+	}
+	return strings.Join(segments, ".")
+}
+
+// setTOMLValueE sets the key path of the TOML document content to value.
+func setTOMLValueE(content []byte, path []string, value any) ([]byte, error) {
+	doc, err := parseTOML(content)
+	if err != nil {
+		return nil, err
+	}
+	lines := doc.lines
+	if err := doc.sectionErrorE(path); err != nil {
+		return nil, err
+	}
+
+	if e := doc.lookup(path); e != nil {
+		first := lines[e.valueLine][:e.valueCol] + inlineValue(value) + lines[e.endLine][e.endCol:]
+		return replaceLines(lines, e.valueLine, e.endLine, first), nil
+	}
+
+	// Insert the key after the last entry of its table
+	parent, key := path[:len(path)-1], path[len(path)-1:]
+	for t := len(doc.tables) - 1; t >= -1; t-- {
+		var tablePath []string
+		after := -1
+		if t >= 0 {
+			tablePath, after = doc.tables[t].path, doc.tables[t].line
+		}
+		if !equalPath(tablePath, parent[:min(len(tablePath), len(parent))]) || len(tablePath) > len(parent) {
+			continue
+		}
+		if t >= 0 && len(tablePath) != len(parent) {
+			// A super-table of parent: parent needs its own table
+			continue
+		}
+		indent := ""
+		if t >= 0 {
+			indent = lines[after][:indentOf(lines[after])]
+		}
+		for _, e := range doc.entries {
+			if e.tableIndex == t {
+				after = e.endLine
+				indent = lines[e.line][:indentOf(lines[e.line])]
+			}
+		}
+		if t == -1 && len(parent) > 0 {
+			// No table of parent: add one at the end of the document
+			break
+		}
+		entry := indent + tomlKey(path[len(tablePath):]) + " = " + inlineValue(value) + "\n"
+		return insertLines(lines, after, entry), nil
+	}
+
+	for i := len(parent); i > 0; i-- {
+		if doc.dottedSection(parent[:i]) {
+			return nil, fmt.Errorf("%s is defined with dotted keys: %w", strings.Join(parent[:i], "."), errUnsupportedConfig)
+		}
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+	if b.Len() > 0 {
+		if !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "[%s]\n%s = %s\n", tomlKey(parent), tomlKey(key), inlineValue(value))
+	return []byte(b.String()), nil
+}
+
+// unsetTOMLValueE removes the key path from the TOML document content. The
+// table headers are kept.
+func unsetTOMLValueE(content []byte, path []string) ([]byte, bool, error) {
+	doc, err := parseTOML(content)
+	if err != nil {
+		return content, false, err
+	}
+	if err := doc.sectionErrorE(path); err != nil {
+		return content, false, err
+	}
+	e := doc.lookup(path)
+	if e == nil {
+		return content, false, nil
+	}
+	return replaceLines(doc.lines, e.line, e.endLine), true, nil
+}
+
+// JSON
+
+// jsonObject is a JSON object keeping the order of its keys.
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// decodeJSON decodes the next JSON value of dec, the objects as *jsonObject
+// and the numbers as json.Number.
+func decodeJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &jsonObject{values: map[string]any{}}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := obj.values[key]; !ok {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = value
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []any{}
+		for dec.More() {
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	default:
+		return tok, nil
+	}
+}
+
+// jsonIndent returns the indentation of the JSON document content: the
+// leading blanks of its second line, or "" for a single line document.
+func jsonIndent(content []byte) string {
+	lines := splitLines(bytes.TrimSpace(content))
+	if len(lines) < 2 {
+		return ""
+	}
+	return lines[1][:indentOf(lines[1])]
+}
+
+// encodeJSON writes value to b, indenting the nested values with indent
+// after prefix, or on a single line if indent is empty.
+func encodeJSON(b *strings.Builder, value any, prefix, indent string) {
+	newline, inner, sep := "", "", ","
+	if indent != "" {
+		newline, inner, sep = "\n"+prefix, "\n"+prefix+indent, ", "
+	}
+	switch v := value.(type) {
+	case *jsonObject:
+		if len(v.keys) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{")
+		for i, key := range v.keys {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(inner + jsonString(key) + ":")
+			if indent != "" {
+				b.WriteString(" ")
+			}
+			encodeJSON(b, v.values[key], prefix+indent, indent)
+		}
+		b.WriteString(newline + "}")
+	case []any:
+		b.WriteString("[")
+		for i, item := range v {
+			if i > 0 {
+				b.WriteString(strings.TrimSpace(sep) + " ")
+			}
+			encodeJSON(b, item, prefix, "")
+		}
+		b.WriteString("]")
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		encodeJSON(b, items, prefix, indent)
+	case string:
+		b.WriteString(jsonString(v))
+	case nil:
+		b.WriteString("null")
+	default:
+		out, _ := json.Marshal(v)
+		b.Write(out)
+	}
+}
+
+// editJSONE decodes the JSON document content, applies edit to its root
+// object and encodes it back with the same indentation and key order.
+func editJSONE(content []byte, edit func(root *jsonObject) bool) ([]byte, bool, error) {
+	root := &jsonObject{values: map[string]any{}}
+	if len(bytes.TrimSpace(content)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		value, err := decodeJSON(dec)
+		if err != nil {
+			return nil, false, err
+		}
+		obj, ok := value.(*jsonObject)
+		if !ok {
+			return nil, false, errors.New("the JSON document is not an object")
+		}
+		root = obj
+	}
+	if !edit(root) {
+		return content, false, nil
+	}
+	indent := jsonIndent(content)
+	if len(bytes.TrimSpace(content)) == 0 {
+		indent = "  "
+	}
+	var b strings.Builder
+	encodeJSON(&b, root, "", indent)
+	b.WriteString("\n")
+	return []byte(b.String()), true, nil
+}
+
+// setJSONValueE sets the key path of the JSON document content to value.
+func setJSONValueE(content []byte, path []string, value any) ([]byte, error) {
+	var errSection error
+	out, _, err := editJSONE(content, func(obj *jsonObject) bool {
+		for i, segment := range path[:len(path)-1] {
+			next, ok := obj.values[segment]
+			if !ok {
+				next = &jsonObject{values: map[string]any{}}
+				obj.keys = append(obj.keys, segment)
+				obj.values[segment] = next
+			}
+			if obj, ok = next.(*jsonObject); !ok {
+				errSection = fmt.Errorf("%s is not a section", strings.Join(path[:i+1], "."))
+				return false
+			}
+		}
+		key := path[len(path)-1]
+		if _, ok := obj.values[key]; !ok {
+			obj.keys = append(obj.keys, key)
+		}
+		obj.values[key] = value
+		return true
+	})
+	if errSection != nil {
+		return nil, errSection
+	}
+	return out, err
+}
+
+// unsetJSONValueE removes the key path from the JSON document content, and
+// the objects left empty.
+func unsetJSONValueE(content []byte, path []string) ([]byte, bool, error) {
+	return editJSONE(content, func(root *jsonObject) bool {
+		var remove func(obj *jsonObject, path []string) bool
+		remove = func(obj *jsonObject, path []string) bool {
+			value, ok := obj.values[path[0]]
+			if !ok {
+				return false
+			}
+			if len(path) > 1 {
+				child, ok := value.(*jsonObject)
+				if !ok || !remove(child, path[1:]) {
+					return false
+				}
+				if len(child.keys) > 0 {
+					return true
+				}
+			}
+			delete(obj.values, path[0])
+			for i, key := range obj.keys {
+				if key == path[0] {
+					obj.keys = append(obj.keys[:i], obj.keys[i+1:]...)
+					break
+				}
+			}
+			return true
+		}
+		return remove(root, path)
+	})
+}
+
+// configValueE converts raw, a value given on the command line for the flag
+// f, to the typed value written to the config file. It returns an error if
+// raw is invalid for f.
+func configValueE(f pflag.Value, raw string) (any, error) {
+	if sliceValue, ok := f.(pflag.SliceValue); ok {
+		items := []string{}
+		if raw != "" {
+			items = strings.Split(raw, ",")
+		}
+		if err := sliceValue.Replace(items); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+	if err := f.Set(raw); err != nil {
+		return nil, err
+	}
+	switch typ := f.Type(); {
+	case typ == "bool":
+		return strconv.ParseBool(raw)
+	case strings.HasPrefix(typ, "int"), strings.HasPrefix(typ, "uint"), typ == "count":
+		return strconv.ParseInt(f.String(), 10, 64)
+	case strings.HasPrefix(typ, "float"):
+		return strconv.ParseFloat(f.String(), 64)
+	default:
+		return raw, nil
+	}
+}
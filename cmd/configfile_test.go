@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestSetConfigValueE checks a key is set in place in each format, keeping the
+// comments, the key order, the indentation and the quoting of the file.
+func TestSetConfigValueE(t *testing.T) {
+	cases := []struct {
+		name    string
+		format  string
+		content string
+		key     string
+		value   any
+		want    string
+	}{
+		{
+			name:   "yaml replace keeps quotes and comment",
+			format: "yaml",
+			content: `# header
+a:
+  b: "old" # comment
+
+  c: 'x'
+`,
+			key:   "a.b",
+			value: "new",
+			want: `# header
+a:
+  b: "new" # comment
+
+  c: 'x'
+`,
+		},
+		{
+			name:    "yaml replace single quoted",
+			format:  "yaml",
+			content: "a:\n  c: 'x'\n",
+			key:     "a.c",
+			value:   "it's",
+			want:    "a:\n  c: 'it''s'\n",
+		},
+		{
+			name:    "yaml replace plain bool",
+			format:  "yaml",
+			content: "a:\n    pretty: true # only for JSON\n",
+			key:     "a.pretty",
+			value:   false,
+			want:    "a:\n    pretty: false # only for JSON\n",
+		},
+		{
+			name:    "yaml insert after the last key of the section",
+			format:  "yaml",
+			content: "a:\n    b:\n        c: 1\n        #d: 2\n    e: 3\n",
+			key:     "a.b.d",
+			value:   "x",
+			want:    "a:\n    b:\n        c: 1\n        d: \"x\"\n        #d: 2\n    e: 3\n",
+		},
+		{
+			name:    "yaml insert missing sections",
+			format:  "yaml",
+			content: "a:\n  b: 1",
+			key:     "a.c.d",
+			value:   []string{"x", "y"},
+			want:    "a:\n  b: 1\n  c:\n    d: [\"x\", \"y\"]\n",
+		},
+		{
+			name:    "yaml insert in empty section",
+			format:  "yaml",
+			content: "a:\n  b:\nz: 1\n",
+			key:     "a.b.c",
+			value:   "x",
+			want:    "a:\n  b:\n    c: \"x\"\nz: 1\n",
+		},
+		{
+			name:    "yaml replace list",
+			format:  "yaml",
+			content: "a:\n  l:\n  - x\n  - y\n  b: 1\n",
+			key:     "a.l",
+			value:   []string{"z"},
+			want:    "a:\n  l: [\"z\"]\n  b: 1\n",
+		},
+		{
+			name:    "yaml empty document",
+			format:  "yaml",
+			content: "",
+			key:     "a.b",
+			value:   "x",
+			want:    "a:\n  b: \"x\"\n",
+		},
+		{
+			name:   "toml replace keeps comment",
+			format: "toml",
+			content: `[a]
+  b = "old" # comment
+
+  [a.c]
+  d = true
+`,
+			key:   "a.b",
+			value: "new",
+			want: `[a]
+  b = "new" # comment
+
+  [a.c]
+  d = true
+`,
+		},
+		{
+			name:    "toml replace multi-line array",
+			format:  "toml",
+			content: "[a]\nl = [\n  \"x\",\n]\nb = 1\n",
+			key:     "a.l",
+			value:   []string{"y"},
+			want:    "[a]\nl = [\"y\"]\nb = 1\n",
+		},
+		{
+			name:    "toml insert after the last key of the table",
+			format:  "toml",
+			content: "[a]\n  b = 1\n\n  [a.c]\n  d = 2\n",
+			key:     "a.e",
+			value:   "x",
+			want:    "[a]\n  b = 1\n  e = \"x\"\n\n  [a.c]\n  d = 2\n",
+		},
+		{
+			name:    "toml insert missing table",
+			format:  "toml",
+			content: "[a]\nb = 1\n",
+			key:     "a.zu-lu.c",
+			value:   int64(2),
+			want:    "[a]\nb = 1\n\n[a.zu-lu]\nc = 2\n",
+		},
+		{
+			name:    "toml insert table of implicit super-table",
+			format:  "toml",
+			content: "[a.c.x]\nk = 1\n",
+			key:     "a.c.e",
+			value:   "x",
+			want:    "[a.c.x]\nk = 1\n\n[a.c]\ne = \"x\"\n",
+		},
+		{
+			name:    "json keeps order and indent",
+			format:  "json",
+			content: "{\n    \"z\": {\n        \"b\": 1.50,\n        \"a\": [1, 2]\n    },\n    \"a\": {}\n}\n",
+			key:     "z.c",
+			value:   "x",
+			want:    "{\n    \"z\": {\n        \"b\": 1.50,\n        \"a\": [1, 2],\n        \"c\": \"x\"\n    },\n    \"a\": {}\n}\n",
+		},
+		{
+			name:    "json missing sections",
+			format:  "json",
+			content: `{"a": 1}`,
+			key:     "b.c",
+			value:   true,
+			want:    "{\"a\":1,\"b\":{\"c\":true}}\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := setConfigValueE(c.format, []byte(c.content), strings.Split(c.key, "."), c.value)
+			if err != nil {
+				t.Fatalf("setConfigValueE() error: %v", err)
+			}
+			if string(got) != c.want {
+				t.Errorf("setConfigValueE() =\n%s\nexpected\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+// TestUnsetConfigValueE checks a key is removed with its value, and the
+// sections left empty with it.
+func TestUnsetConfigValueE(t *testing.T) {
+	cases := []struct {
+		name      string
+		format    string
+		content   string
+		key       string
+		want      string
+		wantFound bool
+	}{
+		{"yaml", "yaml", "a:\n  # keep\n  b: 1\n  c: 2\n", "a.b", "a:\n  # keep\n  c: 2\n", true},
+		{"yaml multi-line value", "yaml", "a:\n  b:\n    - 1\n    - 2\n  c: 2\n", "a.b", "a:\n  c: 2\n", true},
+		{"yaml empty section", "yaml", "a:\n  b:\n    c: 1\nz: 1\n", "a.b.c", "z: 1\n", true},
+		{"yaml missing", "yaml", "a:\n  b: 1\n", "a.c", "a:\n  b: 1\n", false},
+		{"toml", "toml", "[a]\nb = 1 # c\nc = [\n  1,\n]\n", "a.c", "[a]\nb = 1 # c\n", true},
+		{"toml missing", "toml", "[a]\nb = 1\n", "a.c", "[a]\nb = 1\n", false},
+		{"json", "json", "{\n  \"a\": {\n    \"b\": 1\n  },\n  \"c\": 2\n}\n", "a.b", "{\n  \"c\": 2\n}\n", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, found, err := unsetConfigValueE(c.format, []byte(c.content), strings.Split(c.key, "."))
+			if err != nil {
+				t.Fatalf("unsetConfigValueE() error: %v", err)
+			}
+			if string(got) != c.want || found != c.wantFound {
+				t.Errorf("unsetConfigValueE() = %q, %v, expected %q, %v", got, found, c.want, c.wantFound)
+			}
+		})
+	}
+}
+
+// TestConfigValueE_Unsupported checks the constructs the edits do not support
+// are reported as such instead of being edited.
+func TestConfigValueE_Unsupported(t *testing.T) {
+	cases := []struct {
+		name    string
+		format  string
+		content string
+		key     string
+		unset   bool
+	}{
+		{"yaml flow mapping", "yaml", "a: {b: 1}\n", "a.c", false},
+		{"yaml flow mapping unset", "yaml", "a: {b: 1}\n", "a.b", true},
+		{"yaml alias", "yaml", "x: &x\n  b: 1\na: *x\n", "a.c", false},
+		{"yaml multi-document", "yaml", "a:\n  b: 1\n---\nc: 2\n", "a.b", false},
+		{"toml array of tables", "toml", "[[a]]\nb = 1\n", "c.d", false},
+		{"toml inline table", "toml", "a = {b = 1}\n", "a.c", false},
+		{"toml inline table unset", "toml", "a = {b = 1}\n", "a.b", true},
+		{"toml dotted keys", "toml", "[a]\nc.d = 1\n", "a.c.e", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var err error
+			if c.unset {
+				_, _, err = unsetConfigValueE(c.format, []byte(c.content), strings.Split(c.key, "."))
+			} else {
+				_, err = setConfigValueE(c.format, []byte(c.content), strings.Split(c.key, "."), "x")
+			}
+			if !errors.Is(err, errUnsupportedConfig) {
+				t.Errorf("got error %v, expected %v", err, errUnsupportedConfig)
+			}
+		})
+	}
+}
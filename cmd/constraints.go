@@ -0,0 +1,192 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+// ConstraintKind is a kind of constraint between the flags of a command.
+type ConstraintKind string
+
+const (
+	// ConstraintMutuallyExclusive allows at most one of the flags to be set.
+	ConstraintMutuallyExclusive ConstraintKind = "mutually exclusive"
+	// ConstraintRequiredTogether requires all the flags to be set if one is.
+	ConstraintRequiredTogether ConstraintKind = "required together"
+	// ConstraintOneRequired requires at least one of the flags to be set.
+	ConstraintOneRequired ConstraintKind = "one required"
+	// ConstraintRequired requires each of the flags to be set.
+	ConstraintRequired ConstraintKind = "required"
+)
+
+// constraint is a constraint between flags of a command.
+type constraint struct {
+	kind  ConstraintKind
+	flags []string
+}
+
+// MarkConfigMutuallyExclusive allows at most one of the flags of cmd to be
+// set, from any config layer. A flag set on the command line wins over the
+// others set in the env variables, dotenv files or config file, like for any
+// flag: only the command line is checked then, by cobra.
+//
+// The flags can be local or inherited flags of cmd.
+func (c *cli) MarkConfigMutuallyExclusive(cmd *cobra.Command, flags ...string) {
+	c.constraints[cmd] = append(c.constraints[cmd], constraint{kind: ConstraintMutuallyExclusive, flags: flags})
+	cmd.MarkFlagsMutuallyExclusive(flags...)
+}
+
+// MarkConfigRequiredTogether requires all the flags of cmd to be set, from any
+// config layer, if one of them is.
+func (c *cli) MarkConfigRequiredTogether(cmd *cobra.Command, flags ...string) {
+	c.constraints[cmd] = append(c.constraints[cmd], constraint{kind: ConstraintRequiredTogether, flags: flags})
+}
+
+// MarkConfigOneRequired requires at least one of the flags of cmd to be set,
+// from any config layer.
+func (c *cli) MarkConfigOneRequired(cmd *cobra.Command, flags ...string) {
+	c.constraints[cmd] = append(c.constraints[cmd], constraint{kind: ConstraintOneRequired, flags: flags})
+}
+
+// MarkConfigRequired requires each of the flags of cmd to be set, from any
+// config layer: unlike cobra's MarkFlagRequired, a value from an env
+// variable, a dotenv file or the config file satisfies it. A missing flag is a
+// *UsageError wrapping a *RequiredFlagError.
+func (c *cli) MarkConfigRequired(cmd *cobra.Command, flags ...string) {
+	c.constraints[cmd] = append(c.constraints[cmd], constraint{kind: ConstraintRequired, flags: flags})
+}
+
+// flagOwner returns the command declaring the flag name of cmd: cmd for its
+// local flags, the ancestor declaring it for its inherited flags.
+func flagOwner(cmd *cobra.Command, name string) *cobra.Command {
+	if cmd.LocalFlags().Lookup(name) != nil {
+		return cmd
+	}
+	return persistentFlagOwner(cmd, name)
+}
+
+// flagSetting returns the config key of the flag name of cmd and the layer
+// its value is resolved from, and whether the flag is set: resolved from
+// another layer than the defaults, to another value than the zero value of
+// its type.
+func (c *cli) flagSetting(cmd *cobra.Command, name string) (setting ConstraintSetting, set bool) {
+	f := cmd.Flags().Lookup(name)
+	setting.Key = SectionPath(flagOwner(cmd, name)) + "." + name
+	setting.Source = c.sources[setting.Key]
+	if setting.Source == "" {
+		// The flags of the commands skipping the config only come from the
+		// command line
+		setting.Source = SourceDefault
+		if f.Changed {
+			setting.Source = SourceFlag
+		}
+	}
+	zero := slices.Contains([]string{"", "false", "0", "0s", "[]"}, f.Value.String())
+	return setting, setting.Source != SourceDefault && !zero
+}
+
+// exclusiveFlag returns the flag of flags set on cmd, preferring the one set
+// on the command line, or "" if none is set. The flags are expected to be
+// mutually exclusive (see MarkConfigMutuallyExclusive).
+func (c *cli) exclusiveFlag(cmd *cobra.Command, flags ...string) string {
+	found := ""
+	for _, name := range flags {
+		setting, set := c.flagSetting(cmd, name)
+		if !set {
+			continue
+		}
+		if setting.Source == SourceFlag {
+			return name
+		}
+		if found == "" {
+			found = name
+		}
+	}
+	return found
+}
+
+// checkConstraintsE checks the constraints of cmd on the values of its flags
+// resolved from all the config layers. It returns a *ConstraintError for each
+// broken constraint, and a *UsageError for the missing required flags.
+//
+// It is run for each command of the path of the executed command, after
+// InitCommandConfigE and before the init hooks.
+func (c *cli) checkConstraintsE(cmd *cobra.Command) error {
+	var errs, required []error
+	for _, constraint := range c.constraints[cmd] {
+		if constraint.kind == ConstraintRequired {
+			for _, name := range constraint.flags {
+				if _, ok := c.flagSetting(cmd, name); !ok {
+					required = append(required, c.requiredFlagError(cmd, name))
+				}
+			}
+			continue
+		}
+
+		var set []ConstraintSetting
+		var missing []string
+		onCommandLine := false
+		for _, name := range constraint.flags {
+			setting, ok := c.flagSetting(cmd, name)
+			if !ok {
+				missing = append(missing, setting.Key)
+				continue
+			}
+			set = append(set, setting)
+			onCommandLine = onCommandLine || setting.Source == SourceFlag
+		}
+
+		var broken bool
+		switch constraint.kind {
+		case ConstraintMutuallyExclusive:
+			broken = len(set) > 1 && !onCommandLine
+		case ConstraintRequiredTogether:
+			broken = len(set) > 0 && len(missing) > 0
+		case ConstraintOneRequired:
+			broken = len(set) == 0
+		}
+		if broken {
+			errs = append(errs, &ConstraintError{Kind: constraint.kind, Set: set, Missing: missing})
+		}
+	}
+	if len(required) > 0 {
+		errs = append(errs, &UsageError{Err: errors.Join(required...)})
+	}
+	return errors.Join(errs...)
+}
+
+// requiredFlagError returns the *RequiredFlagError of the flag name of cmd,
+// with its env variable and config key unless its command skips the config.
+func (c *cli) requiredFlagError(cmd *cobra.Command, name string) *RequiredFlagError {
+	owner := flagOwner(cmd, name)
+	err := &RequiredFlagError{Flag: name}
+	if !skipConfig(owner) {
+		err.EnvVar = envVarName(SectionEnvPrefix(owner), name)
+		err.Key = SectionPath(owner) + "." + name
+	}
+	return err
+}
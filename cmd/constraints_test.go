@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// TestCheckConstraintsE checks the constraints between flags are evaluated on
+// the values resolved from all the config layers.
+func TestCheckConstraintsE(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		env    map[string]string
+		args   []string
+		want   string
+	}{
+		{"valid", "", nil, []string{"--a", "x", "--b", "y"}, ""},
+		{"exclusive across sources", "cobravsviper:\n  constrained:\n    c: z\n", map[string]string{"COBRAVSVIPER_CONSTRAINED_A": "x"}, []string{"--b", "y"},
+			"cobravsviper.constrained.a (from env) and cobravsviper.constrained.c (from config) are mutually exclusive"},
+		{"command line wins", "cobravsviper:\n  constrained:\n    c: z\n", nil, []string{"--a", "x", "--b", "y"}, ""},
+		{"zero value is not set", "", map[string]string{"COBRAVSVIPER_CONSTRAINED_D": "false"}, []string{"--a", "x", "--b", "y"}, ""},
+		{"exclusive bool", "cobravsviper:\n  constrained:\n    b: y\n", map[string]string{"COBRAVSVIPER_CONSTRAINED_D": "true"}, []string{"--a", "x"},
+			"cobravsviper.constrained.b (from config) and cobravsviper.constrained.d (from env) are mutually exclusive"},
+		{"required together", "", nil, []string{"--a", "x"}, "cobravsviper.constrained.a (from flag) must be set together with cobravsviper.constrained.b"},
+		{"one required", "", nil, nil, "one of cobravsviper.constrained.a, cobravsviper.constrained.c must be set"},
+		{"one required from config", "cobravsviper:\n  constrained:\n    a: x\n    b: y\n", nil, nil, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: env, Fs: newTestFs(t, tc.config)})
+			rootCmd := newRootCmd(c)
+			cmd := &cobra.Command{Use: "constrained", RunE: func(cmd *cobra.Command, args []string) error { return nil }}
+			for _, name := range []string{"a", "b", "c"} {
+				cmd.Flags().String(name, "", "")
+			}
+			cmd.Flags().Bool("d", false, "")
+			rootCmd.AddCommand(cmd)
+			c.MarkConfigMutuallyExclusive(cmd, "a", "c")
+			c.MarkConfigMutuallyExclusive(cmd, "b", "d")
+			c.MarkConfigRequiredTogether(cmd, "a", "b")
+			c.MarkConfigOneRequired(cmd, "a", "c")
+
+			result := c.execute(context.Background(), rootCmd, append([]string{"constrained"}, tc.args...))
+			if tc.want == "" {
+				if result.ExitCode != ExitOK {
+					t.Errorf("got %+v, expected exit code %d", result, ExitOK)
+				}
+				return
+			}
+			var constraintErr *ConstraintError
+			if result.ExitCode != ExitValidation || !errors.As(result.Err, &constraintErr) || constraintErr.Error() != tc.want {
+				t.Errorf("got %+v, expected exit code %d and error %q", result, ExitValidation, tc.want)
+			}
+		})
+	}
+}
+
+// TestMarkConfigRequired checks a required flag is satisfied by any config
+// layer, and that the error of a missing one lists the ways to set it.
+func TestMarkConfigRequired(t *testing.T) {
+	const want = "required flag --token is not set: set it with --token, the COBRAVSVIPER_CONSTRAINED_TOKEN env variable or the cobravsviper.constrained.token config key"
+	cases := []struct {
+		name     string
+		config   string
+		env      map[string]string
+		args     []string
+		exitCode int
+	}{
+		{"flag", "", nil, []string{"--token", "x"}, ExitOK},
+		{"env", "", map[string]string{"COBRAVSVIPER_CONSTRAINED_TOKEN": "x"}, nil, ExitOK},
+		{"config", "cobravsviper:\n  constrained:\n    token: x\n", nil, nil, ExitOK},
+		{"missing", "", nil, nil, ExitUsage},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: env, Fs: newTestFs(t, tc.config)})
+			rootCmd := newRootCmd(c)
+			cmd := &cobra.Command{Use: "constrained", RunE: func(cmd *cobra.Command, args []string) error { return nil }}
+			cmd.Flags().String("token", "", "")
+			rootCmd.AddCommand(cmd)
+			c.MarkConfigRequired(cmd, "token")
+
+			result := c.execute(context.Background(), rootCmd, append([]string{"constrained"}, tc.args...))
+			if result.ExitCode != tc.exitCode {
+				t.Fatalf("got %+v, expected exit code %d", result, tc.exitCode)
+			}
+			var requiredErr *RequiredFlagError
+			if tc.exitCode != ExitOK && (!errors.As(result.Err, &requiredErr) || requiredErr.Error() != want) {
+				t.Errorf("got error %v, expected %q", result.Err, want)
+			}
+		})
+	}
+}
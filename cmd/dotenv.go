@@ -0,0 +1,155 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// defaultDotEnvFile is the dotenv file loaded from the current working directory
+// when the user opts in with --dotenv or COBRAVSVIPER_DOTENV=true.
+const defaultDotEnvFile = ".env"
+
+// getenv retrieves the value of the real environment variable named by the key,
+// from the injected environment (see Options.Env) or from the process.
+func (c *cli) getenv(key string) (string, bool) {
+	if c.opts.Env != nil {
+		value, ok := c.opts.Env[key]
+		return value, ok
+	}
+	return os.LookupEnv(key)
+}
+
+// lookupEnv retrieves the value of the environment variable named by the key.
+// Real environment variables always win over the variables loaded from the
+// dotenv files.
+func (c *cli) lookupEnv(key string) (string, bool) {
+	if value, ok := c.getenv(key); ok {
+		return value, true
+	}
+	value, ok := c.dotEnv[key]
+	return value, ok
+}
+
+// homeDir returns the home directory of the user, from the HOME variable of
+// the injected environment if any.
+func (c *cli) homeDir() (string, error) {
+	if c.opts.Env != nil {
+		if home, ok := c.opts.Env["HOME"]; ok && home != "" {
+			return home, nil
+		}
+	}
+	return homedir.Dir()
+}
+
+// envVarName returns the environment variable name of a flag for a given
+// section env prefix, replacing "-" with "_" in the flag name.
+//
+// Example: prefix COBRAVSVIPER_GRP2CMD2_SUB221 and flag sub221flag1 gives
+// COBRAVSVIPER_GRP2CMD2_SUB221_SUB221FLAG1.
+func envVarName(sectionEnvPrefix string, flagName string) string {
+	return sectionEnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// dotEnvFiles returns the ordered list of dotenv files to load.
+//
+// If the "--dotenv" flag is set (or the COBRAVSVIPER_DOTENV environment variable
+// is true), the ".env" file of the current working directory comes first.
+// Then come the files of the repeatable "--env-file" flag or, if that flag is
+// not set, the files listed in the COBRAVSVIPER_ENV_FILE environment variable
+// (separated by the OS path list separator). Files loaded later win.
+func (c *cli) dotEnvFiles(cmd *cobra.Command) ([]string, error) {
+	var files []string
+
+	useDefault := c.loadDefaultDotEnv
+	if !cmd.PersistentFlags().Lookup("dotenv").Changed {
+		if envVar, ok := c.getenv("COBRAVSVIPER_DOTENV"); ok {
+			b, err := strconv.ParseBool(envVar)
+			if err != nil {
+				return nil, &ValidationError{Key: "COBRAVSVIPER_DOTENV", Source: SourceEnv, Err: err}
+			}
+			useDefault = b
+		}
+	}
+	if useDefault {
+		if _, err := c.opts.Fs.Stat(defaultDotEnvFile); err == nil {
+			files = append(files, defaultDotEnvFile)
+		} else {
+			c.log.Tracef("No %s file found in the current working directory", defaultDotEnvFile)
+		}
+	}
+
+	if cmd.PersistentFlags().Lookup("env-file").Changed {
+		files = append(files, c.envFiles...)
+	} else if envVar, ok := c.getenv("COBRAVSVIPER_ENV_FILE"); ok && envVar != "" {
+		files = append(files, filepath.SplitList(envVar)...)
+	}
+
+	return files, nil
+}
+
+// LoadDotEnvFilesE reads the dotenv files selected by the user (see dotEnvFiles)
+// and returns their variables. When a variable is defined in several files, the
+// last file wins.
+//
+// The dotenv files are only parsed: the environment is left untouched, so real
+// environment variables keep their priority over the dotenv files.
+//
+// It returns a *ConfigNotFoundError if a file does not exist and a
+// *ConfigParseError if a file cannot be parsed.
+func (c *cli) LoadDotEnvFilesE(cmd *cobra.Command) (map[string]string, error) {
+	files, err := c.dotEnvFiles(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for _, file := range files {
+		c.log.Tracef("Loading dotenv file: %s", file)
+		f, err := c.opts.Fs.Open(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &ConfigNotFoundError{File: file, Err: err}
+		} else if err != nil {
+			return nil, fmt.Errorf("error opening dotenv file: %w", err)
+		}
+		vars, err := godotenv.Parse(f)
+		f.Close()
+		if err != nil {
+			return nil, newConfigParseError(c.opts.Fs, file, err)
+		}
+		for key, value := range vars {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// testDotEnvVar is the environment variable of the sub221flag1 flag.
+const testDotEnvVar = "COBRAVSVIPER_GRP2CMD2_SUB221_SUB221FLAG1"
+
+// newTestDotEnvFs returns an in-memory filesystem holding the config file and
+// the dotenv files used by the dotenv tests.
+func newTestDotEnvFs(t *testing.T) afero.Fs {
+	t.Helper()
+	fs := newTestFs(t, "cobravsviper:\n  grp2cmd2:\n    sub221:\n      sub221flag1: value from config\n")
+	files := map[string]string{
+		".env":      testDotEnvVar + "=value from .env\n",
+		"/a.env":    testDotEnvVar + "=value from a.env\n",
+		"/b.env":    testDotEnvVar + "=value from b.env\n",
+		"/conf.env": "COBRAVSVIPER_CONFIG=" + testConfigFile + "\n",
+		"/bad.env":  testDotEnvVar + "=\"unterminated\n",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, name, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write dotenv file: %v", err)
+		}
+	}
+	return fs
+}
+
+// TestLoadDotEnvFilesE runs "grp2cmd2 sub221" with the dotenv files selected
+// by the --dotenv and --env-file flags and their environment variables, and
+// checks the value and source of sub221flag1 and the exit code.
+func TestLoadDotEnvFilesE(t *testing.T) {
+	envFileList := "/a.env" + string(filepath.ListSeparator) + "/b.env"
+
+	cases := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		exitCode int
+		value    string
+		source   Source
+	}{
+		{"no dotenv file", nil, nil, ExitOK, "value from default", SourceDefault},
+		{"dotenv flag", []string{"--dotenv"}, nil, ExitOK, "value from .env", SourceDotEnv},
+		{"dotenv flag false", []string{"--dotenv=false"}, map[string]string{"COBRAVSVIPER_DOTENV": "true"}, ExitOK, "value from default", SourceDefault},
+		{"dotenv env", nil, map[string]string{"COBRAVSVIPER_DOTENV": "true"}, ExitOK, "value from .env", SourceDotEnv},
+		{"dotenv env false", nil, map[string]string{"COBRAVSVIPER_DOTENV": "false"}, ExitOK, "value from default", SourceDefault},
+		{"invalid dotenv flag", []string{"--dotenv=maybe"}, nil, ExitUsage, "", ""},
+		{"invalid dotenv env", nil, map[string]string{"COBRAVSVIPER_DOTENV": "maybe"}, ExitValidation, "", ""},
+		{"env file", []string{"--env-file", "/a.env"}, nil, ExitOK, "value from a.env", SourceDotEnv},
+		{"last env file wins", []string{"--env-file", "/a.env", "--env-file", "/b.env"}, nil, ExitOK, "value from b.env", SourceDotEnv},
+		{"last env file wins reversed", []string{"--env-file", "/b.env", "--env-file", "/a.env"}, nil, ExitOK, "value from a.env", SourceDotEnv},
+		{"env file after .env", []string{"--dotenv", "--env-file", "/a.env"}, nil, ExitOK, "value from a.env", SourceDotEnv},
+		{"env file list env", nil, map[string]string{"COBRAVSVIPER_ENV_FILE": envFileList}, ExitOK, "value from b.env", SourceDotEnv},
+		{"env file flag over env", []string{"--env-file", "/a.env"}, map[string]string{"COBRAVSVIPER_ENV_FILE": "/b.env"}, ExitOK, "value from a.env", SourceDotEnv},
+		{"env over dotenv", []string{"--env-file", "/a.env"}, map[string]string{testDotEnvVar: "value from env"}, ExitOK, "value from env", SourceEnv},
+		{"config from dotenv", []string{"--env-file", "/conf.env"}, nil, ExitOK, "value from config", SourceConfig},
+		{"dotenv over config", []string{"--env-file", "/conf.env", "--env-file", "/a.env"}, nil, ExitOK, "value from a.env", SourceDotEnv},
+		{"missing env file", []string{"--env-file", "/nope.env"}, nil, ExitConfigNotFound, "", ""},
+		{"missing env file in list", nil, map[string]string{"COBRAVSVIPER_ENV_FILE": "/a.env" + string(filepath.ListSeparator) + "/nope.env"}, ExitConfigNotFound, "", ""},
+		{"invalid env file", []string{"--env-file", "/bad.env"}, nil, ExitConfigParse, "", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			env := c.env
+			if env == nil {
+				env = map[string]string{}
+			}
+			var out, errOut bytes.Buffer
+			cl := newCLI(Options{IOStreams: IOStreams{In: strings.NewReader(""), Out: &out, Err: &errOut}, Env: env, Fs: newTestDotEnvFs(t)})
+			args := append(c.args, "grp2cmd2", "sub221", "-o", "json")
+			result := cl.execute(context.Background(), newRootCmd(cl), args)
+
+			if result.ExitCode != c.exitCode {
+				t.Fatalf("execute(%q) = %+v, expected exit code %d\n%s", args, result, c.exitCode, errOut.String())
+			}
+			if c.exitCode != ExitOK {
+				return
+			}
+
+			var got CommandResult
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse output %q: %v", out.String(), err)
+			}
+			for _, f := range got.Flags {
+				if f.Flag == "sub221flag1" {
+					if f.Value != c.value || f.Source != c.source {
+						t.Errorf("sub221flag1 = %q from %s, want %q from %s", f.Value, f.Source, c.value, c.source)
+					}
+					return
+				}
+			}
+			t.Errorf("sub221flag1 missing from output %q", out.String())
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/afero"
+)
+
+// UsageError is returned when the command line is invalid: unknown command,
+// unknown flag, invalid flag value, wrong number of arguments, missing
+// required flag or conflicting flags.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigNotFoundError is returned when a config or dotenv file explicitly
+// requested by the user (with a flag or an environment variable) does not
+// exist. A missing config file in the default locations is not an error.
+type ConfigNotFoundError struct {
+	// File is the path of the missing file.
+	File string
+	Err  error
+}
+
+func (e *ConfigNotFoundError) Error() string {
+	return fmt.Sprintf("config file %s not found: %v", e.File, e.Err)
+}
+
+func (e *ConfigNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigParseError is returned when a config or dotenv file exists but cannot
+// be parsed.
+type ConfigParseError struct {
+	// File is the path of the file that failed to parse.
+	File string
+	// Line is the line of the syntax error, starting at 1, or 0 if the
+	// parser does not report it.
+	Line int
+	Err  error
+}
+
+func (e *ConfigParseError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("failed to parse config file %s at line %d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("failed to parse config file %s: %v", e.File, e.Err)
+}
+
+func (e *ConfigParseError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when a resolved config value cannot be decoded
+// into the type of its flag or is not one of the accepted values.
+type ValidationError struct {
+	// Key is the full config key path, e.g. "cobravsviper.version.pretty".
+	Key string
+	// Source is the config layer the invalid value comes from, or empty if
+	// unknown.
+	Source Source
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("invalid value for %s (from %s): %v", e.Key, e.Source, e.Err)
+	}
+	return fmt.Sprintf("invalid value for %s: %v", e.Key, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ConstraintSetting is a flag of a broken constraint set in a config layer.
+type ConstraintSetting struct {
+	// Key is the full config key path of the flag.
+	Key    string
+	Source Source
+}
+
+func (s ConstraintSetting) String() string {
+	return fmt.Sprintf("%s (from %s)", s.Key, s.Source)
+}
+
+// ConstraintError is returned when the values of flags resolved from all the
+// config layers break a constraint between them (see
+// MarkConfigMutuallyExclusive, MarkConfigRequiredTogether and
+// MarkConfigOneRequired).
+type ConstraintError struct {
+	Kind ConstraintKind
+	// Set are the flags of the constraint that are set, with their source,
+	// and Missing the keys of the ones that are not.
+	Set     []ConstraintSetting
+	Missing []string
+}
+
+func (e *ConstraintError) Error() string {
+	set := make([]string, len(e.Set))
+	for i, setting := range e.Set {
+		set[i] = setting.String()
+	}
+	switch e.Kind {
+	case ConstraintMutuallyExclusive:
+		return fmt.Sprintf("%s are mutually exclusive", joinWords(set))
+	case ConstraintRequiredTogether:
+		return fmt.Sprintf("%s must be set together with %s", joinWords(set), joinWords(e.Missing))
+	default:
+		return fmt.Sprintf("one of %s must be set", strings.Join(e.Missing, ", "))
+	}
+}
+
+// RequiredFlagError is returned, wrapped in a *UsageError, when a flag
+// required from any config layer (see MarkConfigRequired) is not set.
+type RequiredFlagError struct {
+	Flag string
+	// EnvVar and Key are the env variable and the full config key path of
+	// the flag, empty if its command skips the config.
+	EnvVar string
+	Key    string
+}
+
+func (e *RequiredFlagError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("required flag --%s is not set", e.Flag)
+	}
+	return fmt.Sprintf("required flag --%s is not set: set it with --%s, the %s env variable or the %s config key", e.Flag, e.Flag, e.EnvVar, e.Key)
+}
+
+// joinWords joins words as in a sentence: "a", "a and b", "a, b and c".
+func joinWords(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// yamlLineRegexp matches the line reported in the yaml parser errors, e.g.
+// "yaml: line 3: mapping values are not allowed in this context".
+var yamlLineRegexp = regexp.MustCompile(`line (\d+)`)
+
+// newConfigParseError returns a ConfigParseError for the file, extracting the
+// line of the syntax error from the TOML, JSON or YAML parser error.
+func newConfigParseError(fs afero.Fs, file string, err error) *ConfigParseError {
+	parseErr := &ConfigParseError{File: file, Err: err}
+
+	var tomlErr *toml.DecodeError
+	var jsonErr *json.SyntaxError
+	switch {
+	case errors.As(err, &tomlErr):
+		parseErr.Line, _ = tomlErr.Position()
+	case errors.As(err, &jsonErr):
+		// The JSON parser reports a byte offset: count the lines before it
+		if content, readErr := afero.ReadFile(fs, file); readErr == nil && int(jsonErr.Offset) <= len(content) {
+			parseErr.Line = bytes.Count(content[:jsonErr.Offset], []byte("\n")) + 1
+		}
+	default:
+		if m := yamlLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+			parseErr.Line, _ = strconv.Atoi(m[1])
+		}
+	}
+	return parseErr
+}
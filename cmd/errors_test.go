@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nicop311/cobravsviper/pkg/version"
+	"github.com/spf13/afero"
+)
+
+// TestReadViperConfigE_ParseError checks a config file syntax error is
+// returned as a *ConfigParseError holding the file and the line of the error,
+// whatever the config format.
+func TestReadViperConfigE_ParseError(t *testing.T) {
+	cases := []struct {
+		file    string
+		content string
+		line    int
+	}{
+		{"/cobravsviper.conf.yaml", "cobravsviper:\n  rootflag1: a\n  rootflag1: b\n", 3},
+		{"/cobravsviper.conf.toml", "[cobravsviper]\nrootflag1 = \"a\"\nrootflag2 = \n", 3},
+		{"/cobravsviper.conf.json", "{\n  \"cobravsviper\": {\n    \"rootflag1\": \"a\",\n  }\n}\n", 4},
+	}
+
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			if err := afero.WriteFile(fs, c.file, []byte(c.content), 0o644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+			cl := newCLI(Options{IOStreams: IOStreams{Err: io.Discard}, Env: map[string]string{"COBRAVSVIPER_CONFIG": c.file}, Fs: fs})
+			err := cl.ReadViperConfigE(newRootCmd(cl))
+
+			var parseErr *ConfigParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected a *ConfigParseError, got %T: %v", err, err)
+			}
+			if parseErr.File != c.file || parseErr.Line != c.line {
+				t.Errorf("got file %s line %d, expected file %s line %d", parseErr.File, parseErr.Line, c.file, c.line)
+			}
+			if ExitCode(fmt.Errorf("wrapped: %w", err)) != ExitConfigParse {
+				t.Errorf("expected exit code %d for %v", ExitConfigParse, err)
+			}
+		})
+	}
+}
+
+// TestReadViperConfigE_NotFound checks a missing config file set with the flag
+// or the environment variable is returned as a *ConfigNotFoundError holding
+// the file, with or without file extension.
+func TestReadViperConfigE_NotFound(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+	}{
+		{"flag", []string{"--config", "/nope.yaml"}, nil, "/nope.yaml"},
+		{"flag without extension", []string{"--config", "/nope"}, nil, "/nope"},
+		{"env", nil, map[string]string{"COBRAVSVIPER_CONFIG": "/nope.yaml"}, "/nope.yaml"},
+		{"env without extension", nil, map[string]string{"COBRAVSVIPER_CONFIG": "/nope"}, "/nope"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			env := c.env
+			if env == nil {
+				env = map[string]string{}
+			}
+			cl := newCLI(Options{IOStreams: IOStreams{Err: io.Discard}, Env: env, Fs: afero.NewMemMapFs()})
+			rootCmd := newRootCmd(cl)
+			if err := rootCmd.ParseFlags(c.args); err != nil {
+				t.Fatalf("failed to parse flags: %v", err)
+			}
+			err := cl.ReadViperConfigE(rootCmd)
+
+			var notFoundErr *ConfigNotFoundError
+			if !errors.As(err, &notFoundErr) {
+				t.Fatalf("expected a *ConfigNotFoundError, got %T: %v", err, err)
+			}
+			if notFoundErr.File != c.file {
+				t.Errorf("got file %s, expected file %s", notFoundErr.File, c.file)
+			}
+			if ExitCode(err) != ExitConfigNotFound {
+				t.Errorf("expected exit code %d for %v", ExitConfigNotFound, err)
+			}
+		})
+	}
+}
+
+// TestInitViperSubCmdE_ValidationError checks an invalid value is returned as
+// a *ValidationError holding the full key path and the layer of the value.
+func TestInitViperSubCmdE_ValidationError(t *testing.T) {
+	cases := []struct {
+		name   string
+		env    map[string]string
+		dotEnv map[string]string
+		config string
+		source Source
+	}{
+		{"config", nil, nil, "cobravsviper:\n  version:\n    pretty: maybe\n", SourceConfig},
+		{"env", map[string]string{"COBRAVSVIPER_VERSION_PRETTY": "maybe"}, nil, "", SourceEnv},
+		{"dotenv", nil, map[string]string{"COBRAVSVIPER_VERSION_PRETTY": "maybe"}, "", SourceDotEnv},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			env := map[string]string{}
+			for key, value := range c.env {
+				env[key] = value
+			}
+			cl := newCLI(Options{IOStreams: IOStreams{Err: io.Discard}, Env: env, Fs: afero.NewMemMapFs()})
+			rootCmd := newRootCmd(cl)
+			versionCmd, _, err := rootCmd.Find([]string{"version"})
+			if err != nil {
+				t.Fatalf("version command not found: %v", err)
+			}
+			cl.v.SetConfigType("yaml")
+			if err := cl.v.ReadConfig(strings.NewReader(c.config)); err != nil {
+				t.Fatalf("failed to read config: %v", err)
+			}
+			if c.dotEnv != nil {
+				cl.dotEnv = c.dotEnv
+			}
+
+			var target ViperFlagsVersion
+			err = cl.InitViperSubCmdE(versionCmd, &target)
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a *ValidationError, got %T: %v", err, err)
+			}
+			if validationErr.Key != "cobravsviper.version.pretty" || validationErr.Source != c.source {
+				t.Errorf("got key %s source %s, expected key cobravsviper.version.pretty source %s", validationErr.Key, validationErr.Source, c.source)
+			}
+			if ExitCode(err) != ExitValidation {
+				t.Errorf("expected exit code %d for %v", ExitValidation, err)
+			}
+		})
+	}
+}
+
+// TestExitCode checks the exit code of each kind of error.
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("boom"), ExitRuntime},
+		{&UsageError{Err: errors.New("unknown flag")}, ExitUsage},
+		{&ConfigNotFoundError{File: "/nope.yaml"}, ExitConfigNotFound},
+		{&ConfigParseError{File: "/invalid.yaml", Line: 3}, ExitConfigParse},
+		{&ValidationError{Key: "cobravsviper.log-level", Source: SourceFlag}, ExitValidation},
+		{&ConstraintError{Kind: ConstraintOneRequired, Missing: []string{"cobravsviper.log-level"}}, ExitValidation},
+		{fmt.Errorf("check: %w", &version.CheckError{Version: "v1.3.0", Constraints: ">= 1.4"}), ExitVersionCheck},
+		{fmt.Errorf("run: %w", context.Canceled), ExitInterrupted},
+	}
+
+	for _, c := range cases {
+		if got := ExitCode(c.err); got != c.want {
+			t.Errorf("ExitCode(%v) = %d, expected %d", c.err, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+
+	"github.com/nicop311/cobravsviper/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by Execute. Scripts wrapping cobravsviper can branch on
+// the kind of failure.
+const (
+	// ExitOK is returned on success.
+	ExitOK = 0
+	// ExitRuntime is returned when the command fails while running.
+	ExitRuntime = 1
+	// ExitUsage is returned when the command line is invalid (see UsageError).
+	ExitUsage = 2
+	// ExitConfigParse is returned when a config or dotenv file cannot be
+	// parsed (see ConfigParseError).
+	ExitConfigParse = 3
+	// ExitValidation is returned when a resolved config value is invalid, or
+	// when resolved values break a constraint between flags (see
+	// ValidationError and ConstraintError).
+	ExitValidation = 4
+	// ExitConfigNotFound is returned when a config or dotenv file requested by
+	// the user does not exist (see ConfigNotFoundError).
+	ExitConfigNotFound = 5
+	// ExitVersionCheck is returned by version --check when the version does
+	// not satisfy the constraints (see version.CheckError).
+	ExitVersionCheck = 6
+	// ExitInterrupted is returned when the command is interrupted (see
+	// ErrInterrupted), following the shell convention of 128 + SIGINT.
+	ExitInterrupted = 130
+)
+
+// Result is the outcome of an Execute call.
+type Result struct {
+	// ExitCode is the code the process should exit with.
+	ExitCode int
+	// Err is the error returned by the command, nil on success.
+	Err error
+}
+
+// ExitCode returns the exit code matching the kind of err, looking through
+// the wrapped errors with errors.As. The exit code of a failed plugin is
+// passed through.
+func ExitCode(err error) int {
+	var usageErr *UsageError
+	var notFoundErr *ConfigNotFoundError
+	var parseErr *ConfigParseError
+	var validationErr *ValidationError
+	var constraintErr *ConstraintError
+	var versionCheckErr *version.CheckError
+	var pluginErr *exec.ExitError
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrInterrupted), errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.As(err, &pluginErr) && pluginErr.ExitCode() > 0:
+		// A plugin exited with an error: exit with the same code
+		return pluginErr.ExitCode()
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &notFoundErr):
+		return ExitConfigNotFound
+	case errors.As(err, &parseErr):
+		return ExitConfigParse
+	case errors.As(err, &validationErr), errors.As(err, &constraintErr):
+		return ExitValidation
+	case errors.As(err, &versionCheckErr):
+		return ExitVersionCheck
+	default:
+		return ExitRuntime
+	}
+}
+
+// Execute builds a new command tree and runs it with the arguments args (the
+// command line without the program name), the standard streams and the
+// environment variables env. If env is nil, the environment of the process is
+// used.
+//
+// Execute never exits the process: errors are printed on streams.Err and
+// returned in the Result, so the CLI can be embedded in other tools or test
+// harnesses. The errors caused by an invalid command line are returned as a
+// *UsageError.
+//
+// The commands run with the context ctx. When ctx is canceled, e.g. by
+// NotifyContext on SIGINT or SIGTERM, the command is given the
+// shutdown grace period (see the --shutdown-grace-period flag) to return and
+// run the shutdown hooks, then Execute returns ExitInterrupted with an error
+// wrapping ErrInterrupted. This is called by main.main().
+func Execute(ctx context.Context, args []string, streams IOStreams, env map[string]string) Result {
+	c := newCLI(Options{IOStreams: streams, Env: env})
+	return c.execute(ctx, newRootCmd(c), args)
+}
+
+// usageError wraps err in a *UsageError if it is one of the errors cobra
+// returns for an invalid command line: unknown command, invalid arguments,
+// missing required flag or conflicting flags. The flag parsing errors are
+// already wrapped by the flag error func of the root command.
+func usageError(rootCmd *cobra.Command, cmd *cobra.Command, args []string, err error) error {
+	if err == nil || ExitCode(err) != ExitRuntime {
+		return err
+	}
+	if _, _, findErr := rootCmd.Find(args); findErr != nil {
+		return &UsageError{Err: err}
+	}
+	if cmd.ValidateArgs(cmd.Flags().Args()) != nil || cmd.ValidateRequiredFlags() != nil || cmd.ValidateFlagGroups() != nil {
+		return &UsageError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExecute checks Execute returns the exit code and error of the command
+// instead of exiting the process.
+func TestExecute(t *testing.T) {
+	dir := t.TempDir()
+	invalidConfig := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalidConfig, []byte("cobravsviper:\n  rootflag1: a\n rootflag2: b\n"), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	invalidValueConfig := filepath.Join(dir, "invalid-value.yaml")
+	if err := os.WriteFile(invalidValueConfig, []byte("cobravsviper:\n  version:\n    pretty: maybe\n"), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		exitCode int
+		hasError bool
+		stdout   string
+	}{
+		{"success", []string{"grp1cmd1"}, nil, ExitOK, false, "grp1cmd1 called\n"},
+		{"unknown command", []string{"nope"}, nil, ExitUsage, true, ""},
+		{"unknown flag", []string{"version", "--nope"}, nil, ExitUsage, true, ""},
+		{"invalid flag value", []string{"version", "--pretty=maybe"}, nil, ExitUsage, true, ""},
+		{"conflicting flags", []string{"version", "--debug", "--log-level", "warn"}, nil, ExitUsage, true, ""},
+		{"missing config file", []string{"--config", "/nope.yaml", "version"}, nil, ExitConfigNotFound, true, ""},
+		{"missing dotenv file", []string{"--env-file", "/nope.env", "version"}, nil, ExitConfigNotFound, true, ""},
+		{"invalid config file", []string{"--config", invalidConfig, "version"}, nil, ExitConfigParse, true, ""},
+		{"invalid config value", []string{"--config", invalidValueConfig, "version"}, nil, ExitValidation, true, ""},
+		{"invalid env value", []string{"version"}, map[string]string{"COBRAVSVIPER_VERSION_PRETTY": "maybe"}, ExitValidation, true, ""},
+		{"invalid log level", []string{"--log-level", "loud", "version"}, nil, ExitValidation, true, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			env := c.env
+			if env == nil {
+				env = map[string]string{}
+			}
+			var out, errOut bytes.Buffer
+			result := Execute(context.Background(), c.args, IOStreams{In: strings.NewReader(""), Out: &out, Err: &errOut}, env)
+
+			if result.ExitCode != c.exitCode || (result.Err != nil) != c.hasError {
+				t.Errorf("Execute(%q) = %+v, expected exit code %d, error? %v", c.args, result, c.exitCode, c.hasError)
+			}
+			if c.stdout != "" && out.String() != c.stdout {
+				t.Errorf("stdout = %q, want %q", out.String(), c.stdout)
+			}
+		})
+	}
+}
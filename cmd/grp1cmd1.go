@@ -6,27 +6,30 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/nicop311/cobravsviper/pkg/output"
 	"github.com/spf13/cobra"
 )
 
-// grp1cmd1Cmd represents the grp1cmd1 command
-var grp1cmd1Cmd = &cobra.Command{
-	Use:   "grp1cmd1",
-	Short: "A brief description of your command",
-	GroupID: "group1",
-	Long: `A longer description that spans multiple lines and likely contains examples
+// newGrp1cmd1Cmd returns the grp1cmd1 command
+func newGrp1cmd1Cmd(c *cli) *cobra.Command {
+	// grp1cmd1Cmd represents the grp1cmd1 command
+	grp1cmd1Cmd := &cobra.Command{
+		Use:     "grp1cmd1",
+		Short:   "A brief description of your command",
+		GroupID: "group1",
+		Long: `A longer description that spans multiple lines and likely contains examples
 and usage of using your command. For example:
 
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("grp1cmd1 called")
-	},
-}
-
-func init() {
-	rootCmd.AddCommand(grp1cmd1Cmd)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.renderE(cmd, c.commandResult(cmd), output.Options{}, func() error {
+				fmt.Fprintln(cmd.OutOrStdout(), "grp1cmd1 called")
+				return nil
+			})
+		},
+	}
 
 	// Here you will define your flags and configuration settings.
 
@@ -37,4 +40,8 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// grp1cmd1Cmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	addOutputFlag(grp1cmd1Cmd, output.Formats)
+
+	return grp1cmd1Cmd
 }
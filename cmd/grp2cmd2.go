@@ -6,21 +6,10 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/sirupsen/logrus"
+	"github.com/nicop311/cobravsviper/pkg/output"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
-var grp2cmd2Flag1 string
-var grp2cmd2Flag2 string
-var grp2cmd2Flag3 string
-var grp2cmd2Flag4 string
-
-var grp2cmd2PersistentFlag1 string
-var grp2cmd2PersistentFlag2 string
-var grp2cmd2PersistentFlag3 string
-var grp2cmd2PersistentFlag4 string
-
 type ViperGrp2cmd2 struct {
 	Grp2cmd2Flag1 string `mapstructure:"grp2cmd2flag1"`
 	Grp2cmd2Flag2 string `mapstructure:"grp2cmd2flag2"`
@@ -33,59 +22,61 @@ type ViperGrp2cmd2 struct {
 	Grp2cmd2PersistentFlag4 string `mapstructure:"grp2cmd2persistentflag4"`
 }
 
-var vprFlgsGrp2cmd2 ViperGrp2cmd2
-
-// grp2cmd2Cmd represents the grp2cmd2 command
-var grp2cmd2Cmd = &cobra.Command{
-	Use:     "grp2cmd2",
-	Short:   "Test Nested Command of 1st level",
-	GroupID: "group2",
-	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if err := InitViperSubCmdE(viper.GetViper(), cmd, &vprFlgsGrp2cmd2); err != nil {
-			logrus.WithField("cobra-cmd", cmd.Use).WithError(err).Error("Error initializing Viper")
-			return err
-		}
-		return nil
-	},
-	Long: `A longer description that spans multiple lines and likely contains examples
+// newGrp2cmd2Cmd returns the grp2cmd2 command and its subcommands
+func newGrp2cmd2Cmd(c *cli) *cobra.Command {
+	var grp2cmd2Flag1 string
+	var grp2cmd2Flag2 string
+	var grp2cmd2Flag3 string
+	var grp2cmd2Flag4 string
+
+	var grp2cmd2PersistentFlag1 string
+	var grp2cmd2PersistentFlag2 string
+	var grp2cmd2PersistentFlag3 string
+	var grp2cmd2PersistentFlag4 string
+
+	var vprFlgsGrp2cmd2 ViperGrp2cmd2
+
+	// grp2cmd2Cmd represents the grp2cmd2 command
+	grp2cmd2Cmd := &cobra.Command{
+		Use:     "grp2cmd2",
+		Short:   "Test Nested Command of 1st level",
+		GroupID: "group2",
+		Long: `A longer description that spans multiple lines and likely contains examples
 and usage of using your command. For example:
 
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		logrus.WithField("cobra-cmd", cmd.Use).Debug("grp2cmd2 subcommand called")
-
-		fmt.Println("")
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("flags from subcommand grp2cmd2")
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("grp2cmd2flag1: %s", vprFlgsGrp2cmd2.Grp2cmd2Flag1)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("grp2cmd2flag2: %s", vprFlgsGrp2cmd2.Grp2cmd2Flag2)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("grp2cmd2flag3: %s", vprFlgsGrp2cmd2.Grp2cmd2Flag3)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("grp2cmd2flag4: %s", vprFlgsGrp2cmd2.Grp2cmd2Flag4)
-
-		fmt.Println("")
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("persistent flags from subcommand grp2cmd2")
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("grp2cmd2persistentflag1: %s", vprFlgsGrp2cmd2.Grp2cmd2PersistentFlag1)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("grp2cmd2persistentflag2: %s", vprFlgsGrp2cmd2.Grp2cmd2PersistentFlag2)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("grp2cmd2persistentflag3: %s", vprFlgsGrp2cmd2.Grp2cmd2PersistentFlag3)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("grp2cmd2persistentflag4: %s", vprFlgsGrp2cmd2.Grp2cmd2PersistentFlag4)
-
-		fmt.Println("")
-
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("Persistent flags from rootCmd")
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootpersistentflag1: %s", vprFlgsRoot.RootPersistentFlag1)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootpersistentflag2: %s", vprFlgsRoot.RootPersistentFlag2)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootpersistentflag3: %s", vprFlgsRoot.RootPersistentFlag3)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootpersistentflag4: %s", vprFlgsRoot.RootPersistentFlag4)
-	},
-}
-
-func init() {
-	// cobra.OnInitialize(func() {
-	// 	ReadViperConfig(viper.GetViper(), grp2cmd2Cmd)
-	// 	InitViperSubCmd(viper.GetViper(), grp2cmd2Cmd, &vprFlgsGrp2cmd2)
-	// })
-	rootCmd.AddCommand(grp2cmd2Cmd)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.logger(cmd).Debug("grp2cmd2 subcommand called")
+
+			return c.renderE(cmd, c.commandResult(cmd), output.Options{}, func() error {
+				fmt.Fprintln(cmd.OutOrStdout(), "flags from subcommand grp2cmd2")
+				c.printFlag(cmd, "grp2cmd2flag1", vprFlgsGrp2cmd2.Grp2cmd2Flag1)
+				c.printFlag(cmd, "grp2cmd2flag2", vprFlgsGrp2cmd2.Grp2cmd2Flag2)
+				c.printFlag(cmd, "grp2cmd2flag3", vprFlgsGrp2cmd2.Grp2cmd2Flag3)
+				c.printFlag(cmd, "grp2cmd2flag4", vprFlgsGrp2cmd2.Grp2cmd2Flag4)
+
+				fmt.Fprintln(cmd.OutOrStdout(), "")
+				fmt.Fprintln(cmd.OutOrStdout(), "persistent flags from subcommand grp2cmd2")
+				c.printFlag(cmd, "grp2cmd2persistentflag1", vprFlgsGrp2cmd2.Grp2cmd2PersistentFlag1)
+				c.printFlag(cmd, "grp2cmd2persistentflag2", vprFlgsGrp2cmd2.Grp2cmd2PersistentFlag2)
+				c.printFlag(cmd, "grp2cmd2persistentflag3", vprFlgsGrp2cmd2.Grp2cmd2PersistentFlag3)
+				c.printFlag(cmd, "grp2cmd2persistentflag4", vprFlgsGrp2cmd2.Grp2cmd2PersistentFlag4)
+
+				fmt.Fprintln(cmd.OutOrStdout(), "")
+
+				fmt.Fprintln(cmd.OutOrStdout(), "Persistent flags from rootCmd")
+				c.printFlag(cmd, "rootpersistentflag1", c.vprFlgsRoot.RootPersistentFlag1)
+				c.printFlag(cmd, "rootpersistentflag2", c.vprFlgsRoot.RootPersistentFlag2)
+				c.printFlag(cmd, "rootpersistentflag3", c.vprFlgsRoot.RootPersistentFlag3)
+				c.printFlag(cmd, "rootpersistentflag4", c.vprFlgsRoot.RootPersistentFlag4)
+				return nil
+			})
+		},
+	}
+
+	c.SetConfigTarget(grp2cmd2Cmd, &vprFlgsGrp2cmd2)
 
 	grp2cmd2Cmd.PersistentFlags().StringVar(&grp2cmd2PersistentFlag1, "grp2cmd2persistentflag1", "value from default", "grp2cmd2 Persistent flag 1")
 	grp2cmd2Cmd.PersistentFlags().StringVar(&grp2cmd2PersistentFlag2, "grp2cmd2persistentflag2", "value from default", "grp2cmd2 Persistent flag 2")
@@ -96,4 +87,13 @@ func init() {
 	grp2cmd2Cmd.Flags().StringVar(&grp2cmd2Flag2, "grp2cmd2flag2", "value from default", "grp2cmd2 flag 2")
 	grp2cmd2Cmd.Flags().StringVar(&grp2cmd2Flag3, "grp2cmd2flag3", "value from default", "grp2cmd2 flag 3")
 	grp2cmd2Cmd.Flags().StringVar(&grp2cmd2Flag4, "grp2cmd2flag4", "value from default", "grp2cmd2 flag 4")
+	addOutputFlag(grp2cmd2Cmd, output.Formats)
+
+	grp2cmd2Cmd.AddCommand(
+		newSub221Cmd(c, &vprFlgsGrp2cmd2),
+		newSub222Cmd(c),
+		newZuLuSub221Cmd(c, &vprFlgsGrp2cmd2),
+	)
+
+	return grp2cmd2Cmd
 }
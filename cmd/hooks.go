@@ -0,0 +1,113 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Hook is run for each command of the path of the executed command. cmd is
+// the command the hook is registered on, which is the executed command or one
+// of its ancestors.
+type Hook func(cmd *cobra.Command, args []string) error
+
+// OnInit registers a hook run before the Run of cmd and of any of its
+// descendants. For the executed command, the init hooks of every command of
+// its path run exactly once, from the root command to the executed command.
+// The hooks of a same command run in their registration order.
+//
+// Commands register their config initialization with OnInit instead of
+// defining a PersistentPreRunE: the root command runs the hooks of the whole
+// path, so no command needs to call the hooks of its parent.
+func (c *cli) OnInit(cmd *cobra.Command, hook Hook) {
+	c.initHooks[cmd] = append(c.initHooks[cmd], hook)
+}
+
+// OnPostRun registers a hook run after the successful Run of cmd and of any of
+// its descendants. The post-run hooks run in the reverse order of the init
+// hooks: from the executed command to the root command, the hooks of a same
+// command in the reverse order of their registration.
+func (c *cli) OnPostRun(cmd *cobra.Command, hook Hook) {
+	c.postRunHooks[cmd] = append(c.postRunHooks[cmd], hook)
+}
+
+// commandPath returns the commands from the root command to cmd.
+func commandPath(cmd *cobra.Command) []*cobra.Command {
+	var path []*cobra.Command
+	for ; cmd != nil; cmd = cmd.Parent() {
+		path = append([]*cobra.Command{cmd}, path...)
+	}
+	return path
+}
+
+// runInitHooksE loads the config layers shared by all the commands, then
+// initializes each command of the path of the executed command cmd, from the
+// root command to cmd: first its config (see InitCommandConfigE), then checks
+// its constraints (see checkConstraintsE), then runs its init hooks. It stops at the first error.
+//
+// It is the PersistentPreRunE of the root command.
+func (c *cli) runInitHooksE(cmd *cobra.Command, args []string) error {
+	// The command line is parsed: do not print the usage for the config and
+	// runtime errors
+	cmd.Root().SilenceUsage = true
+
+	if err := c.loadConfigE(cmd.Root()); err != nil {
+		return err
+	}
+
+	for _, owner := range commandPath(cmd) {
+		if err := c.InitCommandConfigE(owner); err != nil {
+			c.logger(owner).WithError(err).Error("Error initializing Viper")
+			return err
+		}
+		if err := c.checkConstraintsE(owner); err != nil {
+			c.logger(owner).WithError(err).Error("Invalid config")
+			return err
+		}
+		for _, hook := range c.initHooks[owner] {
+			if err := hook(owner, args); err != nil {
+				c.logger(owner).WithError(err).Error("Error initializing command")
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runPostRunHooksE runs the post-run hooks of the path of the executed command
+// cmd, from cmd to the root command. It stops at the first error.
+//
+// It is the PersistentPostRunE of the root command.
+func (c *cli) runPostRunHooksE(cmd *cobra.Command, args []string) error {
+	path := commandPath(cmd)
+	for i := len(path) - 1; i >= 0; i-- {
+		hooks := c.postRunHooks[path[i]]
+		for j := len(hooks) - 1; j >= 0; j-- {
+			if err := hooks[j](path[i], args); err != nil {
+				c.logger(path[i]).WithError(err).Error("Error finalizing command")
+				return err
+			}
+		}
+	}
+	return nil
+}
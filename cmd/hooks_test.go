@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// TestHooks_Order checks the init hooks of every command of the path run
+// exactly once from the root command to the executed command before its Run,
+// and the post-run hooks in the reverse order after it.
+func TestHooks_Order(t *testing.T) {
+	cases := []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: []string{"parent", "child"},
+			want: []string{
+				"init root", "init parent 1", "init parent 2", "init child",
+				"run child",
+				"post-run child", "post-run parent 2", "post-run parent 1", "post-run root",
+			},
+		},
+		{
+			args: []string{"parent"},
+			want: []string{
+				"init root", "init parent 1", "init parent 2",
+				"run parent",
+				"post-run parent 2", "post-run parent 1", "post-run root",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.args[len(tc.args)-1], func(t *testing.T) {
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: map[string]string{}, Fs: afero.NewMemMapFs()})
+			rootCmd := newRootCmd(c)
+
+			var got []string
+			record := func(event string) Hook {
+				return func(cmd *cobra.Command, args []string) error {
+					got = append(got, event)
+					return nil
+				}
+			}
+			run := func(cmd *cobra.Command, args []string) error {
+				got = append(got, "run "+cmd.Use)
+				return nil
+			}
+			parentCmd := &cobra.Command{Use: "parent", RunE: run}
+			childCmd := &cobra.Command{Use: "child", RunE: run}
+			parentCmd.AddCommand(childCmd)
+			rootCmd.AddCommand(parentCmd)
+
+			// Register the hooks of the leaf first: the order only depends on the tree
+			c.OnInit(childCmd, record("init child"))
+			c.OnPostRun(childCmd, record("post-run child"))
+			c.OnInit(parentCmd, record("init parent 1"))
+			c.OnPostRun(parentCmd, record("post-run parent 1"))
+			c.OnInit(parentCmd, record("init parent 2"))
+			c.OnPostRun(parentCmd, record("post-run parent 2"))
+			c.OnInit(rootCmd, record("init root"))
+			c.OnPostRun(rootCmd, record("post-run root"))
+
+			rootCmd.SetArgs(tc.args)
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("hooks ran in order\n%v\nexpected\n%v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// logFieldCobraCmd is the field of the logs holding the command logging.
+const logFieldCobraCmd = "cobra-cmd"
+
+// ecsVersion is the version of the Elastic Common Schema of the ecs format.
+const ecsVersion = "8.11.0"
+
+// logFields are the fields of the logs whose names depend on the log format
+// and can be changed with --log-field-names: the timestamp, the level, the
+// message and the caller of the entries, and the error and command fields of
+// their data.
+var logFields = []string{
+	logrus.FieldKeyTime,
+	logrus.FieldKeyLevel,
+	logrus.FieldKeyMsg,
+	logrus.FieldKeyFile,
+	logrus.ErrorKey,
+	logFieldCobraCmd,
+}
+
+// logFieldNames maps each of the logFields to its name in the logs.
+type logFieldNames map[string]string
+
+// data returns the name in the logs of the data field key: its mapped name
+// for the error and command fields, key otherwise.
+func (n logFieldNames) data(key string) string {
+	if name, ok := n[key]; ok && (key == logrus.ErrorKey || key == logFieldCobraCmd) {
+		return name
+	}
+	return key
+}
+
+// logFormatOptions are the options of the formatters of the log formats.
+type logFormatOptions struct {
+	// names are the names of the logFields
+	names logFieldNames
+	// console is set for the console, and color when it is colored
+	console, color bool
+	// timestampFormat is the --log-timestamp-format of the timestamps (see
+	// timestampLayout), in the time zone location
+	timestampFormat string
+	location        *time.Location
+}
+
+// logFormat is a format of the logs, usable by --log-format and
+// --log-file-format.
+type logFormat struct {
+	name        string
+	description string
+	// fields are the default names of the logFields not named after
+	// themselves.
+	fields map[string]string
+	// newFormatter returns the formatter of the format, with the names of all
+	// the logFields.
+	newFormatter func(opts logFormatOptions) logrus.Formatter
+}
+
+// logFormats is the registry of the log formats, in the order they are
+// documented and completed.
+var logFormats = []logFormat{
+	{
+		name:        "text",
+		description: "human readable text, without timestamp on the console by default",
+		newFormatter: func(opts logFormatOptions) logrus.Formatter {
+			layout := timestampLayout(opts.timestampFormat)
+			if opts.console && opts.timestampFormat == "" {
+				layout = ""
+			}
+			return &logrus.TextFormatter{
+				ForceColors:      opts.color,
+				DisableColors:    !opts.color,
+				DisableTimestamp: layout == "",
+				FullTimestamp:    true,
+				TimestampFormat:  layout,
+				FieldMap:         fieldMap(opts.names),
+				CallerPrettyfier: callerFileLine,
+			}
+		},
+	},
+	{
+		name:        "logfmt",
+		description: "key=value pairs",
+		newFormatter: func(opts logFormatOptions) logrus.Formatter {
+			layout := timestampLayout(opts.timestampFormat)
+			return &logrus.TextFormatter{
+				DisableColors:    true,
+				DisableTimestamp: layout == "",
+				FullTimestamp:    true,
+				TimestampFormat:  layout,
+				QuoteEmptyFields: true,
+				FieldMap:         fieldMap(opts.names),
+				CallerPrettyfier: callerFileLine,
+			}
+		},
+	},
+	{
+		name:        "json",
+		description: "one JSON object per line",
+		newFormatter: func(opts logFormatOptions) logrus.Formatter {
+			layout := timestampLayout(opts.timestampFormat)
+			return &logrus.JSONFormatter{
+				DisableTimestamp: layout == "",
+				TimestampFormat:  layout,
+				FieldMap:         fieldMap(opts.names),
+				CallerPrettyfier: callerFileLine,
+			}
+		},
+	},
+	{
+		name:        "ecs",
+		description: "Elastic Common Schema JSON",
+		fields: map[string]string{
+			logrus.FieldKeyTime:  "@timestamp",
+			logrus.FieldKeyLevel: "log.level",
+			logrus.FieldKeyMsg:   "message",
+			logrus.FieldKeyFile:  "log.origin.file.name",
+			logrus.ErrorKey:      "error.message",
+			logFieldCobraCmd:     "labels.cobra_cmd",
+		},
+		newFormatter: func(opts logFormatOptions) logrus.Formatter {
+			return &ecsFormatter{names: opts.names, layout: timestampLayout(opts.timestampFormat)}
+		},
+	},
+	{
+		name:        "otel",
+		description: "OpenTelemetry log data model JSON",
+		fields: map[string]string{
+			logrus.FieldKeyTime:  "timestamp",
+			logrus.FieldKeyLevel: "severityText",
+			logrus.FieldKeyMsg:   "body",
+			logrus.FieldKeyFile:  "code.filepath",
+			logrus.ErrorKey:      "exception.message",
+			logFieldCobraCmd:     "cobra.cmd",
+		},
+		newFormatter: func(opts logFormatOptions) logrus.Formatter {
+			return &otelFormatter{names: opts.names, layout: timestampLayout(opts.timestampFormat)}
+		},
+	},
+}
+
+// timestampLayouts are the named layouts of --log-timestamp-format.
+var timestampLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"datetime":    time.DateTime,
+	"stampmilli":  time.StampMilli,
+	"none":        "",
+}
+
+// timestampLayout returns the time layout of the --log-timestamp-format
+// format: RFC3339 with nanoseconds if empty, the layout of a named format,
+// "" for none, or format itself, a Go time layout.
+func timestampLayout(format string) string {
+	if format == "" {
+		return time.RFC3339Nano
+	}
+	if layout, ok := timestampLayouts[strings.ToLower(format)]; ok {
+		return layout
+	}
+	return format
+}
+
+// callerFileLine returns the caller of an entry as its file name and line,
+// without its function.
+func callerFileLine(frame *runtime.Frame) (function string, file string) {
+	return "", fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+}
+
+// logFormatNames returns the names of the log formats of the registry.
+func logFormatNames() []string {
+	names := make([]string, 0, len(logFormats))
+	for _, format := range logFormats {
+		names = append(names, format.name)
+	}
+	return names
+}
+
+// logLevelNames returns the names of the logrus levels, from the most
+// verbose.
+func logLevelNames() []string {
+	var names []string
+	for _, level := range slices.Backward(logrus.AllLevels) {
+		names = append(names, level.String())
+	}
+	return names
+}
+
+// completeLogFormats completes the flags taking a log format with the formats
+// of the registry.
+func completeLogFormats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var completions []string
+	for _, format := range logFormats {
+		completions = append(completions, format.name+"\t"+format.description)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeLogLevels completes the flags taking a log level with the logrus
+// levels.
+func completeLogLevels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return logLevelNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// parseLogFieldNamesE parses the field=name pairs of --log-field-names.
+func parseLogFieldNamesE(pairs []string) (logFieldNames, error) {
+	names := logFieldNames{}
+	for _, pair := range pairs {
+		field, name, ok := strings.Cut(pair, "=")
+		field, name = strings.TrimSpace(field), strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid field name %q, expected field=name", pair)
+		}
+		if !slices.Contains(logFields, field) {
+			return nil, fmt.Errorf("unknown log field %q, expected one of %s", field, strings.Join(logFields, ", "))
+		}
+		names[field] = name
+	}
+	return names, nil
+}
+
+// newLogFormatter returns the formatter of the log format of the registry.
+// The names of the fields are the ones of the format, overridden by opts.names.
+func newLogFormatter(format string, opts logFormatOptions) (logrus.Formatter, error) {
+	i := slices.IndexFunc(logFormats, func(f logFormat) bool { return f.name == format })
+	if i < 0 {
+		return nil, fmt.Errorf("unknown log format %q, expected one of %s", format, strings.Join(logFormatNames(), ", "))
+	}
+
+	names := logFieldNames{}
+	for _, field := range logFields {
+		names[field] = field
+		if name, ok := logFormats[i].fields[field]; ok {
+			names[field] = name
+		}
+		if name, ok := opts.names[field]; ok {
+			names[field] = name
+		}
+	}
+	opts.names = names
+	if opts.location == nil {
+		opts.location = time.Local
+	}
+	return &entryFormatter{Formatter: logFormats[i].newFormatter(opts), names: names, location: opts.location}, nil
+}
+
+// fieldMap returns the logrus field map renaming the timestamp, the level, the
+// message and the caller of the entries.
+func fieldMap(names logFieldNames) logrus.FieldMap {
+	return logrus.FieldMap{
+		logrus.FieldKeyTime:  names[logrus.FieldKeyTime],
+		logrus.FieldKeyLevel: names[logrus.FieldKeyLevel],
+		logrus.FieldKeyMsg:   names[logrus.FieldKeyMsg],
+		logrus.FieldKeyFile:  names[logrus.FieldKeyFile],
+	}
+}
+
+// entryFormatter renames the error and command fields of the entries and
+// converts their time to the time zone location before formatting them with
+// the formatter of a log format.
+type entryFormatter struct {
+	logrus.Formatter
+	names    logFieldNames
+	location *time.Location
+}
+
+// Format formats a copy of entry with the renamed fields.
+func (f *entryFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	renamed := *entry
+	renamed.Time = entry.Time.In(f.location)
+	renamed.Data = make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		renamed.Data[f.names.data(key)] = value
+	}
+	return f.Formatter.Format(&renamed)
+}
+
+// ecsFormatter formats the entries as Elastic Common Schema JSON objects, with
+// the data fields at the top level.
+type ecsFormatter struct {
+	names  logFieldNames
+	layout string
+}
+
+// Format formats entry as one line of JSON.
+func (f *ecsFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	record := map[string]any{"ecs.version": ecsVersion}
+	for key, value := range entry.Data {
+		record[key] = logValue(value)
+	}
+	if f.layout != "" {
+		record[f.names[logrus.FieldKeyTime]] = entry.Time.Format(f.layout)
+	}
+	if entry.HasCaller() {
+		record[f.names[logrus.FieldKeyFile]] = filepath.Base(entry.Caller.File)
+		record["log.origin.file.line"] = entry.Caller.Line
+	}
+	record[f.names[logrus.FieldKeyLevel]] = entry.Level.String()
+	record[f.names[logrus.FieldKeyMsg]] = entry.Message
+	return marshalLogRecordE(record)
+}
+
+// otelSeverityNumbers are the OpenTelemetry severity numbers of the logrus
+// levels.
+var otelSeverityNumbers = map[logrus.Level]int{
+	logrus.TraceLevel: 1,
+	logrus.DebugLevel: 5,
+	logrus.InfoLevel:  9,
+	logrus.WarnLevel:  13,
+	logrus.ErrorLevel: 17,
+	logrus.FatalLevel: 21,
+	logrus.PanicLevel: 24,
+}
+
+// otelFormatter formats the entries as OpenTelemetry log records, with the
+// data fields as attributes.
+type otelFormatter struct {
+	names  logFieldNames
+	layout string
+}
+
+// Format formats entry as one line of JSON.
+func (f *otelFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	record := map[string]any{
+		f.names[logrus.FieldKeyLevel]: strings.ToUpper(entry.Level.String()),
+		"severityNumber":              otelSeverityNumbers[entry.Level],
+		f.names[logrus.FieldKeyMsg]:   entry.Message,
+	}
+	if f.layout != "" {
+		record[f.names[logrus.FieldKeyTime]] = entry.Time.Format(f.layout)
+	}
+	attributes := make(map[string]any, len(entry.Data))
+	for key, value := range entry.Data {
+		attributes[key] = logValue(value)
+	}
+	if entry.HasCaller() {
+		attributes[f.names[logrus.FieldKeyFile]] = filepath.Base(entry.Caller.File)
+		attributes["code.lineno"] = entry.Caller.Line
+	}
+	if len(attributes) > 0 {
+		record["attributes"] = attributes
+	}
+	return marshalLogRecordE(record)
+}
+
+// logValue returns the value of a data field as encoded in JSON: the message
+// of errors, which encode to an empty object otherwise.
+func logValue(value any) any {
+	if err, ok := value.(error); ok {
+		return err.Error()
+	}
+	return value
+}
+
+// marshalLogRecordE encodes record as one line of JSON.
+func marshalLogRecordE(record map[string]any) ([]byte, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the log entry to JSON: %w", err)
+	}
+	return append(line, '\n'), nil
+}
@@ -0,0 +1,475 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Defaults of the --log-file-* flags.
+const (
+	defaultLogFileFormat     = "json"
+	defaultLogFileMaxSize    = 100 // megabytes
+	defaultLogFileMaxAge     = 28  // days
+	defaultLogFileMaxBackups = 3
+)
+
+// logSink is a destination of the logs, with its own format and level. The
+// sinks are logrus hooks of the logger of the tree, which writes nothing
+// itself.
+type logSink struct {
+	out       io.Writer
+	formatter logrus.Formatter
+	level     logrus.Level
+	// fixedLevel is set when the sink keeps its level for the commands with
+	// their own log level, e.g. with --log-file-level
+	fixedLevel bool
+}
+
+// Levels returns the levels logged to the sink: its level and the more
+// severe ones.
+func (s *logSink) Levels() []logrus.Level {
+	return logrus.AllLevels[:s.level+1]
+}
+
+// Fire writes the entry to the sink.
+func (s *logSink) Fire(entry *logrus.Entry) error {
+	line, err := s.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.out.Write(line)
+	return err
+}
+
+// verbosityFlags are the root persistent flags setting the level of the
+// console logs.
+var verbosityFlags = []string{"log-level", "debug", "verbose", "quiet"}
+
+// consoleLogLevelE returns the level of the console logs, set by one of the
+// verbosityFlags: --log-level, --debug for debug, -v for debug, -vv for trace,
+// -q for warning, -qq for error and -qqq for fatal. The verbosityFlags are
+// mutually exclusive from any config layer, a flag of the command line
+// winning over the others.
+func (c *cli) consoleLogLevelE(rootCmd *cobra.Command) (logrus.Level, error) {
+	flags := c.vprFlgsRoot
+	switch c.exclusiveFlag(rootCmd, verbosityFlags...) {
+	case "debug":
+		// harcode that the --debug flags set logrus level to debug
+		return logrus.DebugLevel, nil
+	case "verbose":
+		return logrus.Level(min(int(logrus.InfoLevel)+flags.Verbose, int(logrus.TraceLevel))), nil
+	case "quiet":
+		return logrus.Level(max(int(logrus.InfoLevel)-flags.Quiet, int(logrus.PanicLevel))), nil
+	}
+	// get the log level from viper which is bind to the cobra flag --log-level
+	level, err := logrus.ParseLevel(flags.LogLevel)
+	if err != nil {
+		return 0, c.rootValidationError(rootCmd, "log-level", err)
+	}
+	return level, nil
+}
+
+// logTimezoneE returns the time zone of --log-timezone: the local one if
+// empty, or a name of the IANA Time Zone database such as UTC or
+// Europe/Paris.
+func logTimezoneE(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// rootValidationError returns a *ValidationError for the root persistent flag
+// name, with the source of its value.
+func (c *cli) rootValidationError(rootCmd *cobra.Command, name string, err error) *ValidationError {
+	key := SectionPath(rootCmd) + "." + name
+	return &ValidationError{Key: key, Source: c.sources[key], Err: err}
+}
+
+// initLogging configures the logger and the shutdown of the tree from the
+// resolved root persistent flags: the console sink, writing to the error
+// stream of the tree, and the file sink if --log-file is set, each with its
+// own format and level, and the log levels of the commands.
+func (c *cli) initLogging(rootCmd *cobra.Command) error {
+	c.setShutdownGracePeriod(c.vprFlgsRoot.ShutdownGracePeriod)
+
+	fieldNames, err := parseLogFieldNamesE(c.vprFlgsRoot.LogFieldNames)
+	if err != nil {
+		err := c.rootValidationError(rootCmd, "log-field-names", err)
+		c.log.WithError(err).Error("invalid log field names")
+		return err
+	}
+
+	if err := validateColorModeE(c.vprFlgsRoot.Color); err != nil {
+		err := c.rootValidationError(rootCmd, "color", err)
+		c.log.WithError(err).Error("unknown color mode")
+		return err
+	}
+
+	location, err := logTimezoneE(c.vprFlgsRoot.LogTimezone)
+	if err != nil {
+		err := c.rootValidationError(rootCmd, "log-timezone", err)
+		c.log.WithError(err).Error("unknown log timezone")
+		return err
+	}
+	opts := logFormatOptions{
+		names:           fieldNames,
+		console:         true,
+		color:           c.colorEnabled(c.opts.Err),
+		timestampFormat: c.vprFlgsRoot.LogTimestampFormat,
+		location:        location,
+	}
+
+	// Set logs format
+	formatter, err := newLogFormatter(c.vprFlgsRoot.LogFormat, opts)
+	if err != nil {
+		err := c.rootValidationError(rootCmd, "log-format", err)
+		c.log.WithError(err).Error("unknown log format")
+		return err
+	}
+
+	// Initialize logrus log level and log format for all cobra commands and subcommands.
+	level, err := c.consoleLogLevelE(rootCmd)
+	if err != nil {
+		c.log.WithError(err).Error("invalid log level")
+		return err
+	}
+	c.logCaller = c.vprFlgsRoot.LogCaller || (c.exclusiveFlag(rootCmd, verbosityFlags...) == "verbose" && c.vprFlgsRoot.Verbose >= 3)
+	console := &logSink{out: c.opts.Err, formatter: formatter, level: level}
+
+	opts.console, opts.color = false, false
+	file, err := c.newLogFileSinkE(rootCmd, level, opts)
+	if err != nil {
+		c.log.WithError(err).Error("invalid log file")
+		return err
+	}
+
+	c.logFile = file
+	c.logSinks = []*logSink{console}
+	if file != nil {
+		c.logSinks = append(c.logSinks, file)
+	}
+	c.setLogSinks(c.log, level)
+
+	logLevels, err := c.commandLogLevelsE(rootCmd)
+	if err != nil {
+		c.log.WithError(err).Error("invalid command log level")
+		return err
+	}
+	c.logMu.Lock()
+	c.logLevels = logLevels
+	clear(c.levelLoggers)
+	c.logMu.Unlock()
+
+	c.log.Debugf("logrus output format is set to: %s", c.vprFlgsRoot.LogFormat)
+	c.log.Debugf("logrus log-level is set to: %s", level)
+	if file != nil {
+		c.log.Debugf("logging to file %s with format %s and level %s", c.vprFlgsRoot.LogFile, c.vprFlgsRoot.LogFileFormat, file.level)
+	}
+
+	// Debugging: Show all loaded settings
+	c.log.Tracef("Viper settings: %+v", c.v.AllSettings())
+
+	return nil
+}
+
+// newLogFileSinkE returns the sink of the --log-file file, or nil if the flag
+// is not set. The file is rotated when it reaches --log-file-max-size, and
+// every --log-file-rotate-every if set; the rotated files are removed after
+// --log-file-max-age or beyond --log-file-max-backups, and compressed with
+// --log-file-compress. The sink level is --log-file-level, or consoleLevel if
+// not set, and its format has the options opts.
+//
+// The file is closed by a shutdown hook. If the tree already has a sink of the
+// same file, e.g. the one of the shell running the tree, it is returned
+// instead of opening the file again.
+func (c *cli) newLogFileSinkE(rootCmd *cobra.Command, consoleLevel logrus.Level, opts logFormatOptions) (*logSink, error) {
+	flags := c.vprFlgsRoot
+	if flags.LogFile == "" {
+		return nil, nil
+	}
+	if c.logFile != nil {
+		if out, ok := c.logFile.out.(*lumberjack.Logger); ok && out.Filename == flags.LogFile {
+			return c.logFile, nil
+		}
+	}
+
+	formatter, err := newLogFormatter(flags.LogFileFormat, opts)
+	if err != nil {
+		return nil, c.rootValidationError(rootCmd, "log-file-format", err)
+	}
+	level := consoleLevel
+	if flags.LogFileLevel != "" {
+		if level, err = logrus.ParseLevel(flags.LogFileLevel); err != nil {
+			return nil, c.rootValidationError(rootCmd, "log-file-level", err)
+		}
+	}
+	for name, value := range map[string]int{
+		"log-file-max-size":    flags.LogFileMaxSize,
+		"log-file-max-age":     flags.LogFileMaxAge,
+		"log-file-max-backups": flags.LogFileMaxBackups,
+	} {
+		if value < 0 {
+			return nil, c.rootValidationError(rootCmd, name, fmt.Errorf("must not be negative, got %d", value))
+		}
+	}
+	if flags.LogFileRotateEvery < 0 {
+		return nil, c.rootValidationError(rootCmd, "log-file-rotate-every", fmt.Errorf("must not be negative, got %s", flags.LogFileRotateEvery))
+	}
+
+	out := &lumberjack.Logger{
+		Filename:   flags.LogFile,
+		MaxSize:    flags.LogFileMaxSize,
+		MaxAge:     flags.LogFileMaxAge,
+		MaxBackups: flags.LogFileMaxBackups,
+		Compress:   flags.LogFileCompress,
+		LocalTime:  true,
+	}
+
+	stop := make(chan struct{})
+	if flags.LogFileRotateEvery > 0 {
+		go func() {
+			ticker := time.NewTicker(flags.LogFileRotateEvery)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := out.Rotate(); err != nil {
+						c.log.WithError(err).Warn("failed to rotate the log file")
+					}
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+	c.OnShutdown("log-file", func(ctx context.Context) error {
+		close(stop)
+		return out.Close()
+	})
+
+	return &logSink{out: out, formatter: formatter, level: level, fixedLevel: flags.LogFileLevel != ""}, nil
+}
+
+// setLogSinks makes log write to the sinks of the tree, the sinks without
+// fixed level logging at level, with the caller of the entries if
+// c.logCaller is set. The logger level is the most verbose of the sinks: each
+// sink filters the entries of its own level.
+func (c *cli) setLogSinks(log *logrus.Logger, level logrus.Level) {
+	hooks := logrus.LevelHooks{}
+	loggerLevel := level
+	for _, sink := range c.logSinks {
+		if !sink.fixedLevel {
+			sink = &logSink{out: sink.out, formatter: sink.formatter, level: level}
+		}
+		hooks.Add(sink)
+		loggerLevel = max(loggerLevel, sink.level)
+	}
+	log.ReplaceHooks(hooks)
+	log.SetOutput(io.Discard)
+	log.SetLevel(loggerLevel)
+	log.SetReportCaller(c.logCaller)
+}
+
+// logger returns the logger of cmd, adding the cobra-cmd field, the name of
+// cmd (see logCommandName), to its entries. It logs at the level of cmd or of
+// its closest ancestor with a log level (see commandLogLevelsE), at the level
+// of the tree otherwise.
+func (c *cli) logger(cmd *cobra.Command) *logrus.Entry {
+	return c.levelLogger(cmd).WithField(logFieldCobraCmd, logCommandName(cmd))
+}
+
+// logCommandName returns the name of cmd in the --log-levels pairs: its
+// command path without the root command, the words separated by dots, e.g.
+// grp2cmd2.sub221. The root command keeps its name.
+func logCommandName(cmd *cobra.Command) string {
+	path := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	return strings.ReplaceAll(path, " ", ".")
+}
+
+// levelLogger returns the logger of the log level of cmd, created on first
+// use, or the logger of the tree if cmd has no log level.
+func (c *cli) levelLogger(cmd *cobra.Command) *logrus.Logger {
+	c.logMu.Lock()
+	defer c.logMu.Unlock()
+	for ; cmd != nil; cmd = cmd.Parent() {
+		level, ok := c.logLevels[cmd]
+		if !ok {
+			continue
+		}
+		log, ok := c.levelLoggers[level]
+		if !ok {
+			log = logrus.New()
+			c.setLogSinks(log, level)
+			c.levelLoggers[level] = log
+		}
+		return log
+	}
+	return c.log
+}
+
+// commandLogLevelKey is the key of the log level of a command in the config
+// section of the command (see commandLogLevelsE).
+const commandLogLevelKey = "log-level"
+
+// logLevelValue is the value of the log-level key of the config section of
+// cmd: a logrus level.
+type logLevelValue struct {
+	cmd   *cobra.Command
+	level string
+}
+
+func (v *logLevelValue) String() string { return v.level }
+
+func (v *logLevelValue) Set(s string) error {
+	if _, err := logrus.ParseLevel(s); err != nil {
+		return err
+	}
+	v.level = s
+	return nil
+}
+
+func (v *logLevelValue) Type() string { return "string" }
+
+// commandLogLevelFlag returns the flag standing for the log-level key of the
+// config section of cmd in the config keys (see configKeys): the key has no
+// flag, --log-levels sets the levels of all the commands.
+func commandLogLevelFlag(cmd *cobra.Command) *pflag.Flag {
+	return &pflag.Flag{
+		Name:  commandLogLevelKey,
+		Usage: fmt.Sprintf("Log level of the %s command and its subcommands. Corresponding environment variable: %s.", logCommandName(cmd), envVarName(SectionEnvPrefix(cmd), commandLogLevelKey)),
+		Value: &logLevelValue{cmd: cmd},
+	}
+}
+
+// commandLogLevel returns the log level set with the log-level key of the
+// config section of cmd, and its source: the env variable of the key wins
+// over the dotenv files, which win over the config file. ok is false when the
+// key is not set.
+func (c *cli) commandLogLevel(cmd *cobra.Command) (value string, source Source, ok bool) {
+	envName := envVarName(SectionEnvPrefix(cmd), commandLogLevelKey)
+	if v, ok := c.getenv(envName); ok {
+		return v, SourceEnv, true
+	} else if v, ok := c.dotEnv[envName]; ok {
+		return v, SourceDotEnv, true
+	} else if key := SectionPath(cmd) + "." + commandLogLevelKey; c.v.InConfig(key) {
+		return c.v.GetString(key), SourceConfig, true
+	}
+	return "", SourceDefault, false
+}
+
+// commandLogLevelsE returns the log levels of the commands of the tree, set
+// with the command=level pairs of --log-levels or with the log-level key of
+// the config section of the command. For a command, --log-levels wins over
+// the env variable of the key, which wins over the config file.
+//
+// The invalid levels and the unknown commands are *ValidationError.
+func (c *cli) commandLogLevelsE(rootCmd *cobra.Command) (map[*cobra.Command]logrus.Level, error) {
+	levels := map[*cobra.Command]logrus.Level{}
+	for _, pair := range c.vprFlgsRoot.LogLevels {
+		command, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, c.rootValidationError(rootCmd, "log-levels", fmt.Errorf("invalid command log level %q, expected command=level", pair))
+		}
+		cmd, args, err := rootCmd.Find(strings.Fields(strings.ReplaceAll(command, ".", " ")))
+		if err != nil || len(args) > 0 || cmd == rootCmd {
+			return nil, c.rootValidationError(rootCmd, "log-levels", fmt.Errorf("unknown command %q", command))
+		}
+		level, err := logrus.ParseLevel(strings.TrimSpace(name))
+		if err != nil {
+			return nil, c.rootValidationError(rootCmd, "log-levels", err)
+		}
+		levels[cmd] = level
+	}
+
+	var errs []error
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+		if _, ok := levels[cmd]; ok || cmd == rootCmd || skipConfig(cmd) {
+			return
+		}
+
+		value, source, ok := c.commandLogLevel(cmd)
+		if !ok {
+			return
+		}
+		key := SectionPath(cmd) + "." + commandLogLevelKey
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			errs = append(errs, &ValidationError{Key: key, Source: source, Err: err})
+			return
+		}
+		c.sources[key] = source
+		levels[cmd] = level
+	}
+	walk(rootCmd)
+	return levels, errors.Join(errs...)
+}
+
+// completeCommandLogLevels completes the command=level pairs of --log-levels:
+// the command paths of the tree, then the logrus levels.
+func completeCommandLogLevels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	done, current := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		done, current = toComplete[:i+1], toComplete[i+1:]
+	}
+
+	var completions []string
+	if command, _, ok := strings.Cut(current, "="); ok {
+		for _, level := range logLevelNames() {
+			if completion := done + command + "=" + level; strings.HasPrefix(completion, toComplete) {
+				completions = append(completions, completion)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	rootCmd := cmd.Root()
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if cmd != rootCmd && cmd.IsAvailableCommand() && !skipConfig(cmd) {
+			if completion := done + logCommandName(cmd) + "="; strings.HasPrefix(completion, toComplete) {
+				completions = append(completions, completion)
+			}
+		}
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(rootCmd)
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestLogFile checks the logs are written to the console and to --log-file,
+// each with its own format and level, set with the flags or the config file.
+func TestLogFile(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "cobravsviper.log")
+	fs := newTestFs(t, `cobravsviper:
+  log-file-level: trace
+`)
+
+	var logs bytes.Buffer
+	c := newCLI(Options{
+		IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &logs},
+		Env:       map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile},
+		Fs:        fs,
+	})
+	result := c.execute(context.Background(), newRootCmd(c), []string{"--log-level=debug", "--log-format=text", "--log-file", logFile, "grp2cmd2", "sub221"})
+	if result.ExitCode != ExitOK {
+		t.Fatalf("got %+v, expected exit code %d", result, ExitOK)
+	}
+
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read the log file: %v", err)
+	}
+	file := string(content)
+	for _, want := range []string{`"level":"trace","msg":"Viper settings: `, `"msg":"sub221 subcommand called"`} {
+		if !strings.Contains(file, want) {
+			t.Errorf("missing %s in the log file:\n%s", want, file)
+		}
+	}
+	if strings.Contains(logs.String(), "Viper settings") {
+		t.Errorf("trace log on the debug console:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "sub221 subcommand called") || strings.Contains(logs.String(), `"msg"`) {
+		t.Errorf("missing text debug log on the console:\n%s", logs.String())
+	}
+}
+
+// TestLogFile_Invalid checks the invalid log file settings are validation
+// errors.
+func TestLogFile_Invalid(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "cobravsviper.log")
+	cases := [][]string{
+		{"--log-file", logFile, "--log-file-level", "loud"},
+		{"--log-file", logFile, "--log-file-format", "xml"},
+		{"--log-file", logFile, "--log-file-max-size", "-1"},
+	}
+
+	for _, args := range cases {
+		c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: map[string]string{}, Fs: newTestFs(t, "")})
+		result := c.execute(context.Background(), newRootCmd(c), append(args, "grp1cmd1"))
+		if result.ExitCode != ExitValidation {
+			t.Errorf("%q: got %+v, expected exit code %d", args, result, ExitValidation)
+		}
+	}
+}
+
+// TestNewLogFormatter checks each format of the registry names the fields its
+// own way, with RFC3339Nano timestamps, and that the names can be overridden.
+func TestNewLogFormatter(t *testing.T) {
+	entry := &logrus.Entry{
+		Time:    time.Date(2025, 1, 2, 3, 4, 5, 600, time.UTC),
+		Level:   logrus.WarnLevel,
+		Message: "hello",
+		Data:    logrus.Fields{logFieldCobraCmd: "sub221", logrus.ErrorKey: errors.New("boom"), "key": "value"},
+	}
+	cases := []struct {
+		format string
+		names  []string
+		want   []string
+	}{
+		{"text", nil, []string{`time="2025-01-02T03:04:05.0000006Z" level=warning msg=hello cobra-cmd=sub221 error=boom key=value`}},
+		{"logfmt", []string{"msg=message", "cobra-cmd=command"}, []string{`level=warning message=hello command=sub221`}},
+		{"json", nil, []string{`"cobra-cmd":"sub221"`, `"error":"boom"`, `"msg":"hello"`, `"time":"2025-01-02T03:04:05.0000006Z"`}},
+		{"ecs", nil, []string{`"@timestamp":"2025-01-02T03:04:05.0000006Z"`, `"ecs.version":"8.11.0"`, `"error.message":"boom"`, `"labels.cobra_cmd":"sub221"`, `"log.level":"warning"`, `"message":"hello"`}},
+		{"otel", []string{"cobra-cmd=cobra.command"}, []string{`"attributes":{"cobra.command":"sub221","exception.message":"boom","key":"value"}`, `"body":"hello"`, `"severityNumber":13`, `"severityText":"WARNING"`, `"timestamp":"2025-01-02T03:04:05.0000006Z"`}},
+	}
+
+	for _, tc := range cases {
+		names, err := parseLogFieldNamesE(tc.names)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		formatter, err := newLogFormatter(tc.format, logFormatOptions{names: names, location: time.UTC})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		line, err := formatter.Format(entry)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(string(line), want) {
+				t.Errorf("%s: missing %s in %s", tc.format, want, line)
+			}
+		}
+	}
+}
+
+// TestLogFormat_Invalid checks the unknown log formats and field names are
+// validation errors.
+func TestLogFormat_Invalid(t *testing.T) {
+	cases := [][]string{
+		{"--log-format", "xml"},
+		{"--log-field-names", "msg"},
+		{"--log-field-names", "caller=where"},
+	}
+
+	for _, args := range cases {
+		c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: map[string]string{}, Fs: newTestFs(t, "")})
+		result := c.execute(context.Background(), newRootCmd(c), append(args, "grp1cmd1"))
+		if result.ExitCode != ExitValidation {
+			t.Errorf("%q: got %+v, expected exit code %d", args, result, ExitValidation)
+		}
+	}
+}
+
+// TestCommandLogLevels checks the commands log at the level of their closest
+// command with a log level, from --log-levels, the env or the config file, and
+// with their --log-levels name in the cobra-cmd field.
+func TestCommandLogLevels(t *testing.T) {
+	config := `cobravsviper:
+  log-level: warn
+  grp2cmd2:
+    log-level: debug
+`
+	cases := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		exitCode int
+		want     string
+		notWant  string
+	}{
+		{"inherited from the parent", nil, []string{"grp2cmd2", "sub221"}, ExitOK, `msg="sub221 subcommand called" cobra-cmd=grp2cmd2.sub221`, ""},
+		{"flag", nil, []string{"--log-levels", "grp2cmd2.sub221=error", "grp2cmd2", "sub221"}, ExitOK, "", "sub221 subcommand called"},
+		{"env", map[string]string{"COBRAVSVIPER_GRP2CMD2_SUB221_LOG_LEVEL": "warn"}, []string{"grp2cmd2", "sub221"}, ExitOK, "", "sub221 subcommand called"},
+		{"debug command", nil, []string{"--log-levels", "version=debug", "version"}, ExitOK, "version subcommand called", "logrus log-level is set to"},
+		{"unknown command", nil, []string{"--log-levels", "nope=debug", "version"}, ExitValidation, "", ""},
+		{"invalid level", map[string]string{"COBRAVSVIPER_GRP2CMD2_LOG_LEVEL": "loud"}, []string{"version"}, ExitValidation, "", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			var logs bytes.Buffer
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &logs}, Env: env, Fs: newTestFs(t, config)})
+			result := c.execute(context.Background(), newRootCmd(c), tc.args)
+			if result.ExitCode != tc.exitCode {
+				t.Fatalf("got %+v, expected exit code %d\n%s", result, tc.exitCode, logs.String())
+			}
+			if tc.want != "" && !strings.Contains(logs.String(), tc.want) {
+				t.Errorf("missing %q in the logs:\n%s", tc.want, logs.String())
+			}
+			if tc.notWant != "" && strings.Contains(logs.String(), tc.notWant) {
+				t.Errorf("unexpected %q in the logs:\n%s", tc.notWant, logs.String())
+			}
+		})
+	}
+}
+
+// TestConsoleLogLevel checks the console log level set by --log-level,
+// --debug, -v or -q, from any source, and that two of them set outside of the
+// command line conflict.
+func TestConsoleLogLevel(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		env      map[string]string
+		args     []string
+		exitCode int
+		want     logrus.Level
+	}{
+		{"default", "", nil, nil, ExitOK, logrus.InfoLevel},
+		{"-v", "", nil, []string{"-v"}, ExitOK, logrus.DebugLevel},
+		{"-vv", "", nil, []string{"-vv"}, ExitOK, logrus.TraceLevel},
+		{"-vvvv", "", nil, []string{"-vvvv"}, ExitOK, logrus.TraceLevel},
+		{"-q", "", nil, []string{"-q"}, ExitOK, logrus.WarnLevel},
+		{"-qqq", "", nil, []string{"-qqq"}, ExitOK, logrus.FatalLevel},
+		{"-qqqqqq", "", nil, []string{"-qqqqqq"}, ExitOK, logrus.PanicLevel},
+		{"verbose env", "", map[string]string{"COBRAVSVIPER_VERBOSE": "2"}, nil, ExitOK, logrus.TraceLevel},
+		{"debug env", "", map[string]string{"COBRAVSVIPER_DEBUG": "true"}, nil, ExitOK, logrus.DebugLevel},
+		{"debug env false", "cobravsviper:\n  quiet: 1\n", map[string]string{"COBRAVSVIPER_DEBUG": "false"}, nil, ExitOK, logrus.WarnLevel},
+		{"flag wins", "cobravsviper:\n  verbose: 1\n", map[string]string{"COBRAVSVIPER_DEBUG": "true"}, []string{"--log-level", "error"}, ExitOK, logrus.ErrorLevel},
+		{"env and config conflict", "cobravsviper:\n  log-level: info\n", map[string]string{"COBRAVSVIPER_DEBUG": "true"}, nil, ExitValidation, 0},
+		{"flags conflict", "", nil, []string{"-v", "-q"}, ExitUsage, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: env, Fs: newTestFs(t, tc.config)})
+			result := c.execute(context.Background(), newRootCmd(c), append(tc.args, "grp1cmd1"))
+			if result.ExitCode != tc.exitCode {
+				t.Fatalf("got %+v, expected exit code %d", result, tc.exitCode)
+			}
+			if tc.exitCode == ExitOK && c.log.GetLevel() != tc.want {
+				t.Errorf("got level %s, expected %s", c.log.GetLevel(), tc.want)
+			}
+		})
+	}
+}
+
+// TestLogTimestampAndCaller checks the format and time zone of the
+// timestamps, and the caller of the logs.
+func TestLogTimestampAndCaller(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		exitCode int
+		want     *regexp.Regexp
+	}{
+		{"console text without timestamp", []string{"-v"}, ExitOK, regexp.MustCompile(`^level=debug msg="sub221 subcommand called"`)},
+		{"console text timestamp", []string{"-v", "--log-timestamp-format", "datetime", "--log-timezone", "UTC"}, ExitOK, regexp.MustCompile(`^time="\d{4}-\d\d-\d\d \d\d:\d\d:\d\d" level=debug`)},
+		{"json default timestamp", []string{"-v", "--log-format", "json", "--log-timezone", "UTC"}, ExitOK, regexp.MustCompile(`"time":"\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?Z"`)},
+		{"json caller", []string{"-v", "--log-format", "json", "--log-caller"}, ExitOK, regexp.MustCompile(`"file":"sub221\.go:\d+"`)},
+		{"-vvv caller", []string{"--log-format", "ecs", "-vvv"}, ExitOK, regexp.MustCompile(`"log.origin.file.line":\d+,"log.origin.file.name":"sub221\.go"`)},
+		{"unknown time zone", []string{"--log-timezone", "Nowhere/Nope"}, ExitValidation, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &logs}, Env: map[string]string{}, Fs: newTestFs(t, "")})
+			result := c.execute(context.Background(), newRootCmd(c), append(tc.args, "grp2cmd2", "sub221"))
+			if result.ExitCode != tc.exitCode {
+				t.Fatalf("got %+v, expected exit code %d", result, tc.exitCode)
+			}
+			if tc.want == nil {
+				return
+			}
+			var found bool
+			for _, line := range strings.Split(logs.String(), "\n") {
+				if !strings.Contains(line, "sub221 subcommand called") {
+					continue
+				}
+				found = true
+				if !tc.want.MatchString(line) {
+					t.Errorf("log line %q does not match %s", line, tc.want)
+				}
+			}
+			if !found {
+				t.Errorf("missing sub221 log line:\n%s", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nicop311/cobravsviper/pkg/output"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// CommandResult is the result of the commands printing their flags: the
+// flags of the command, local and inherited, with their values resolved
+// through the config layers.
+type CommandResult struct {
+	Command string       `json:"command"`
+	Flags   []FlagResult `json:"flags"`
+}
+
+// FlagResult is a flag of a CommandResult.
+type FlagResult struct {
+	Flag string `json:"flag"`
+	// Key is the config key of the flag, e.g. "cobravsviper.grp2cmd2.sub221.sub221flag1".
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source Source `json:"source"`
+}
+
+// Table returns the flags of r, one per row, in the table format.
+func (r CommandResult) Table() (header []string, rows [][]string) {
+	for _, f := range r.Flags {
+		rows = append(rows, []string{f.Flag, envValue(f.Value), string(f.Source), f.Key})
+	}
+	return []string{"flag", "value", "source", "key"}, rows
+}
+
+// addOutputFlag adds the -o/--output flag of a command rendering its result
+// with renderE, documented and completed with formats: output.Formats, or
+// the formats of its kind of result, e.g. version.Formats, given to renderE
+// too.
+func addOutputFlag(cmd *cobra.Command, formats []output.Format) {
+	usage := "Format of the result: " + strings.Join(output.Names(formats), ", ") + ". Human readable text by default."
+	for _, f := range formats {
+		if f.Name == "jsonpath" {
+			usage += " " + output.JSONPathSyntax
+		}
+	}
+	cmd.Flags().StringP("output", "o", "", usage)
+	cmd.RegisterFlagCompletionFunc("output", completeOutputFormats(formats))
+}
+
+// completeOutputFormats returns the completion of -o/--output with formats and
+// their descriptions. The formats taking an argument are completed with "="
+// and no space after them.
+func completeOutputFormats(formats []output.Format) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if strings.Contains(toComplete, "=") {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var completions []string
+		for i, name := range output.Names(formats) {
+			completions = append(completions, name+"\t"+formats[i].Description)
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+}
+
+// renderE writes result, the structured result of cmd, to the output stream
+// in the format of the -o/--output flag of cmd (see addOutputFlag), or calls
+// text to write the human readable output of cmd if the flag is not set. The
+// logs always go to the error stream, so the output can be piped. The
+// template files are read from the file system of the CLI.
+//
+// An unknown format, or a template or JSONPath expression that cannot be
+// parsed, is a *UsageError when it comes from the command line, and a
+// *ValidationError of the output key of cmd otherwise.
+func (c *cli) renderE(cmd *cobra.Command, result any, opts output.Options, text func() error) error {
+	format, _ := cmd.Flags().GetString("output")
+	if format == "" {
+		return text()
+	}
+	opts.Color = c.colorEnabled(cmd.OutOrStdout())
+	opts.ReadFile = func(name string) ([]byte, error) {
+		return afero.ReadFile(c.opts.Fs, name)
+	}
+	p, err := output.NewPrinter(format, opts)
+	if err != nil {
+		setting, _ := c.flagSetting(cmd, "output")
+		if setting.Source == SourceFlag {
+			return &UsageError{Err: fmt.Errorf("invalid argument %q for \"-o, --output\" flag: %w", format, err)}
+		}
+		return &ValidationError{Key: setting.Key, Source: setting.Source, Err: err}
+	}
+	return p.Print(cmd.OutOrStdout(), result)
+}
+
+// printFlag writes the human readable line of the flag name of cmd, resolved
+// to value, to the output stream: "name: value (source)".
+func (c *cli) printFlag(cmd *cobra.Command, name string, value any) {
+	setting, _ := c.flagSetting(cmd, name)
+	fmt.Fprintf(cmd.OutOrStdout(), "%s: %v (%s)\n", name, value, setting.Source)
+}
+
+// commandResult returns the CommandResult of cmd. The help and output flags,
+// and the root persistent flags locating the config layers, are left out.
+func (c *cli) commandResult(cmd *cobra.Command) CommandResult {
+	result := CommandResult{Command: strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "), Flags: []FlagResult{}}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || f.Name == "output" || (flagOwner(cmd, f.Name) == cmd.Root() && isConfigLocationFlag(f.Name)) {
+			return
+		}
+		setting, _ := c.flagSetting(cmd, f.Name)
+		result.Flags = append(result.Flags, FlagResult{Flag: f.Name, Key: setting.Key, Value: flagValue(f), Source: setting.Source})
+	})
+	return result
+}
+
+// flagValue returns the value of f with its type: a list for the slice flags,
+// a bool or a number for the bool and number flags, a string otherwise.
+func flagValue(f *pflag.Flag) any {
+	if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+		return sliceValue.GetSlice()
+	}
+	value := f.Value.String()
+	switch typ := f.Value.Type(); {
+	case typ == "bool":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case strings.HasPrefix(typ, "int"), strings.HasPrefix(typ, "uint"), typ == "count":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case strings.HasPrefix(typ, "float"):
+		if x, err := strconv.ParseFloat(value, 64); err == nil {
+			return x
+		}
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// TestRenderE checks the commands write their result to the output stream in
+// the --output format, set with the flag, the env or the config file, and
+// their human readable output without it.
+func TestRenderE(t *testing.T) {
+	var out bytes.Buffer
+	env := map[string]string{
+		"COBRAVSVIPER_CONFIG":                      testConfigFile,
+		"COBRAVSVIPER_GRP2CMD2_SUB221_SUB221FLAG2": "value from env",
+	}
+	c := newCLI(Options{IOStreams: IOStreams{Out: &out, Err: &bytes.Buffer{}}, Env: env, Fs: newTestFs(t, "cobravsviper:\n  grp2cmd2:\n    sub221:\n      output: json\n")})
+	result := c.execute(context.Background(), newRootCmd(c), []string{"grp2cmd2", "sub221", "--sub221flag1", "value from flag"})
+	if result.ExitCode != ExitOK {
+		t.Fatalf("got %+v", result)
+	}
+
+	var got CommandResult
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON result %q: %v", out.String(), err)
+	}
+	if got.Command != "grp2cmd2 sub221" {
+		t.Errorf("got command %q, expected %q", got.Command, "grp2cmd2 sub221")
+	}
+	want := map[string]FlagResult{
+		"sub221flag1":             {Flag: "sub221flag1", Key: "cobravsviper.grp2cmd2.sub221.sub221flag1", Value: "value from flag", Source: SourceFlag},
+		"sub221flag2":             {Flag: "sub221flag2", Key: "cobravsviper.grp2cmd2.sub221.sub221flag2", Value: "value from env", Source: SourceEnv},
+		"grp2cmd2persistentflag1": {Flag: "grp2cmd2persistentflag1", Key: "cobravsviper.grp2cmd2.grp2cmd2persistentflag1", Value: "value from default", Source: SourceDefault},
+		"debug":                   {Flag: "debug", Key: "cobravsviper.debug", Value: false, Source: SourceDefault},
+	}
+	for _, f := range got.Flags {
+		if f.Flag == "output" || f.Flag == "config" {
+			t.Errorf("got flag %s in the result", f.Flag)
+		}
+		if w, ok := want[f.Flag]; ok {
+			if f != w {
+				t.Errorf("got %+v, expected %+v", f, w)
+			}
+			delete(want, f.Flag)
+		}
+	}
+	for name := range want {
+		t.Errorf("flag %s missing from the result", name)
+	}
+}
+
+// TestRenderE_Formats checks the output of the formats on the commands, and
+// the human readable output without --output, and the completion of the
+// formats.
+func TestRenderE_Formats(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"grp1cmd1"}, "grp1cmd1 called\n"},
+		{[]string{"grp1cmd1", "-o", "jsonpath={.command}"}, "grp1cmd1"},
+		{[]string{"grp2cmd2", "sub221", "-o", "template={{range .Flags}}{{if eq .Flag \"sub221flag3\"}}{{.Value}}{{end}}{{end}}", "--sub221flag3", "x"}, "x"},
+		{[]string{"grp2cmd2", "sub221", "-o", "jsonpath={range .flags[*]}{.flag}={.source}{\"\\n\"}{end}", "--sub221flag3", "x"}, "sub221flag3=flag\n"},
+		{[]string{"version", "-o", "jsonpath={.cobravsviper.major}"}, "0"},
+		{[]string{"version", "-o", "json", "--pretty=false"}, `{"cobravsviper":{"major":0,`},
+		{[]string{"version", "-o", "template={{.Major}}.{{.Minor}}"}, "0.0"},
+		{[]string{"version", "-o", "template-file=/version.tmpl"}, "version 0\n"},
+		{[]string{"version", "-o", "env"}, "COBRAVSVIPER_MAJOR=0\nCOBRAVSVIPER_MINOR=0\n"},
+		{[]string{"version", "-o", "short"}, "\n"},
+		{[]string{"plugin", "list", "-o", "json"}, "[]\n"},
+		{[]string{"__complete", "version", "-o", ""}, "template=\tGo text/template"},
+		{[]string{"__complete", "config", "diff", "-o", ""}, "unified\t"},
+		{[]string{"__complete", "version", "-o", ""}, "env\tKEY=VALUE lines"},
+		{[]string{"__complete", "grp1cmd1", "-o", ""}, "jsonpath=\tJSONPath subset of kubectl, without filters"},
+		{[]string{"grp1cmd1", "--help"}, "filters, slices and recursive descent are not supported"},
+	}
+
+	for _, tc := range cases {
+		var out bytes.Buffer
+		fs := newTestFs(t, "")
+		if err := afero.WriteFile(fs, "/version.tmpl", []byte("version {{.Major}}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		c := newCLI(Options{IOStreams: IOStreams{Out: &out, Err: &bytes.Buffer{}}, Env: map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile, "PATH": ""}, Fs: fs})
+		result := c.execute(context.Background(), newRootCmd(c), tc.args)
+		if result.ExitCode != ExitOK {
+			t.Errorf("%v: got %+v", tc.args, result)
+			continue
+		}
+		if got := out.String(); got != tc.want && !strings.Contains(got, tc.want) {
+			t.Errorf("%v: got output %q, expected %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+// TestRenderE_Invalid checks an invalid --output is a usage error on the
+// command line, and a validation error of the output key of the command, with
+// the layer it comes from, otherwise.
+func TestRenderE_Invalid(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		env      map[string]string
+		args     []string
+		exitCode int
+		source   Source
+	}{
+		{"unknown format flag", "", nil, []string{"version", "-o", "bogus"}, ExitUsage, ""},
+		{"invalid template flag", "", nil, []string{"version", "-o", "template={{.Major"}, ExitUsage, ""},
+		{"unknown format env", "", map[string]string{"COBRAVSVIPER_VERSION_OUTPUT": "bogus"}, []string{"version"}, ExitValidation, SourceEnv},
+		{"invalid template config", "cobravsviper:\n  version:\n    output: template={{.Major\n", nil, []string{"version"}, ExitValidation, SourceConfig},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: env, Fs: newTestFs(t, tc.config)})
+			result := c.execute(context.Background(), newRootCmd(c), tc.args)
+			if result.ExitCode != tc.exitCode {
+				t.Fatalf("got %+v, expected exit code %d", result, tc.exitCode)
+			}
+			if tc.exitCode == ExitUsage {
+				var usageErr *UsageError
+				if !errors.As(result.Err, &usageErr) {
+					t.Errorf("got %+v, expected a usage error", result)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(result.Err, &validationErr) {
+				t.Fatalf("got %+v, expected a validation error", result)
+			}
+			if validationErr.Key != "cobravsviper.version.output" || validationErr.Source != tc.source {
+				t.Errorf("got %+v, expected the version output key from the %s", validationErr, tc.source)
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/nicop311/cobravsviper/pkg/output"
+	"github.com/spf13/afero"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// pluginPrefix is the prefix of the name of the plugin executables: the
+	// plugin "foo" is the executable "cobravsviper-foo" found on PATH.
+	pluginPrefix = "cobravsviper-"
+
+	// pluginGroupID is the help group of the plugin commands.
+	pluginGroupID = "plugins"
+
+	// annotationPlugin is the cobra.Command annotation holding the path of
+	// the executable of a plugin command.
+	annotationPlugin = "cobravsviper/plugin"
+
+	// pluginConfigFD is the file descriptor the JSON snapshot of the config
+	// is written to, given to the plugin in the COBRAVSVIPER_PLUGIN_CONFIG_FD
+	// env variable.
+	pluginConfigFD = 3
+)
+
+// Plugin is an external command found on PATH.
+type Plugin struct {
+	// Name is the name of the command, e.g. "foo" for "cobravsviper-foo".
+	Name string `json:"name"`
+	// Path is the path of the executable.
+	Path string `json:"path"`
+	// Warnings lists the name collisions of the plugin: with a built-in
+	// command, which always wins, or with a plugin of the same name found
+	// earlier on PATH, which wins.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PluginConfig is the JSON snapshot of the resolved root persistent config
+// written to the plugins on the file descriptor COBRAVSVIPER_PLUGIN_CONFIG_FD.
+type PluginConfig struct {
+	// ConfigFile is the config file used, if any.
+	ConfigFile string `json:"configFile,omitempty"`
+	// Settings holds the resolved value of each root persistent flag, keyed
+	// by flag name.
+	Settings map[string]any `json:"settings"`
+}
+
+// FindPlugins returns the plugins found in the directories of the PATH
+// environment variable of the command tree, in PATH order, with their name
+// collisions with the commands of rootCmd and with each other.
+func (c *cli) FindPlugins(rootCmd *cobra.Command) []*Plugin {
+	path, _ := c.getenv("PATH")
+
+	var plugins []*Plugin
+	byName := map[string]*Plugin{}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		entries, err := afero.ReadDir(c.opts.Fs, dir)
+		if err != nil {
+			c.log.Tracef("skip PATH directory %s: %v", dir, err)
+			continue
+		}
+		for _, entry := range entries {
+			name := c.pluginName(entry)
+			if name == "" {
+				continue
+			}
+			p := &Plugin{Name: name, Path: filepath.Join(dir, entry.Name())}
+			if builtin := builtinCommand(rootCmd, p.Name); builtin != nil {
+				p.Warnings = append(p.Warnings, fmt.Sprintf("%s is overshadowed by the built-in command %q", p.Path, builtin.Name()))
+			}
+			if first, ok := byName[p.Name]; ok {
+				p.Warnings = append(p.Warnings, fmt.Sprintf("%s is overshadowed by a similarly named plugin: %s", p.Path, first.Path))
+			} else {
+				byName[p.Name] = p
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+// defaultPathExt is the PATHEXT of Windows, used when the variable is unset.
+const defaultPathExt = ".COM;.EXE;.BAT;.CMD"
+
+// pluginName returns the name of the plugin of the directory entry, or "" when
+// the entry is not an executable file prefixed with cobravsviper-. On Windows,
+// where a file is executable by its extension, the PATHEXT extension is not
+// part of the name. Elsewhere the name is the whole rest of the file name, so
+// cobravsviper-foo.sh is the plugin foo.sh.
+func (c *cli) pluginName(info os.FileInfo) string {
+	if info.IsDir() || !strings.HasPrefix(info.Name(), pluginPrefix) {
+		return ""
+	}
+	name := strings.TrimPrefix(info.Name(), pluginPrefix)
+	if runtime.GOOS != "windows" {
+		if info.Mode().Perm()&0o111 == 0 {
+			return ""
+		}
+		return name
+	}
+	pathExt, _ := c.getenv("PATHEXT")
+	if pathExt == "" {
+		pathExt = defaultPathExt
+	}
+	return trimPathExt(name, pathExt)
+}
+
+// trimPathExt returns name without its extension when the extension is one of
+// the semicolon-separated pathExt list, compared case-insensitively, or ""
+// when it is not.
+func trimPathExt(name, pathExt string) string {
+	ext := filepath.Ext(name)
+	if ext == "" {
+		return ""
+	}
+	for _, e := range strings.Split(pathExt, ";") {
+		if strings.EqualFold(e, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return ""
+}
+
+// builtinCommand returns the built-in command of rootCmd named or aliased
+// name, or nil. The help and completion commands, added by cobra when the
+// tree is executed, are built-in commands as well.
+func builtinCommand(rootCmd *cobra.Command, name string) *cobra.Command {
+	if name == "help" || name == "completion" {
+		return &cobra.Command{Use: name}
+	}
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Annotations[annotationPlugin] == "" && (cmd.Name() == name || cmd.HasAlias(name)) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// addPluginCmds adds a command to rootCmd for each plugin found on PATH that
+// does not collide with a built-in command nor with a plugin found earlier, so
+// the plugins show up in the help and are completed like built-in commands.
+func (c *cli) addPluginCmds(rootCmd *cobra.Command) {
+	for _, p := range c.FindPlugins(rootCmd) {
+		if len(p.Warnings) > 0 {
+			continue
+		}
+		if !rootCmd.ContainsGroup(pluginGroupID) {
+			rootCmd.AddGroup(&cobra.Group{ID: pluginGroupID, Title: "Plugin Commands:"})
+		}
+		rootCmd.AddCommand(newExternalPluginCmd(c, p))
+	}
+}
+
+// newExternalPluginCmd returns the command running the plugin p. The
+// arguments are passed to the plugin unparsed.
+func newExternalPluginCmd(c *cli, p *Plugin) *cobra.Command {
+	return &cobra.Command{
+		Use:                p.Name,
+		Short:              fmt.Sprintf("Run the %s plugin", p.Path),
+		GroupID:            pluginGroupID,
+		DisableFlagParsing: true,
+		Annotations:        map[string]string{annotationPlugin: p.Path},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.runPluginE(cmd, p, args)
+		},
+	}
+}
+
+// runPluginE runs the executable of the plugin p with the arguments args, the
+// standard streams of the command tree and the environment of the command
+// tree, plus the resolved root persistent config: each root persistent flag
+// as its COBRAVSVIPER_* env variable, and the JSON PluginConfig snapshot on
+// the file descriptor COBRAVSVIPER_PLUGIN_CONFIG_FD, with the values typed as
+// in the -o json output of the commands (see flagValue).
+//
+// On interrupt, the plugin receives an interrupt signal and the shutdown grace
+// period to stop. The exit code of the plugin becomes the exit code of the
+// command (see ExitCode).
+func (c *cli) runPluginE(cmd *cobra.Command, p *Plugin, args []string) error {
+	rootCmd := cmd.Root()
+	snapshot := PluginConfig{ConfigFile: c.v.ConfigFileUsed(), Settings: map[string]any{}}
+	env := c.environ()
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		value := flagValue(f)
+		snapshot.Settings[f.Name] = value
+		env = append(env, envVarName(SectionEnvPrefix(rootCmd), f.Name)+"="+envValue(value))
+	})
+	if snapshot.ConfigFile != "" {
+		env = append(env, "COBRAVSVIPER_CONFIG="+snapshot.ConfigFile)
+	}
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the plugin config: %w", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create the plugin config pipe: %w", err)
+	}
+	defer r.Close()
+	env = append(env, "COBRAVSVIPER_PLUGIN_CONFIG_FD="+strconv.Itoa(pluginConfigFD))
+
+	pluginCmd := exec.CommandContext(cmd.Context(), p.Path, args...)
+	pluginCmd.Stdin = cmd.InOrStdin()
+	pluginCmd.Stdout = cmd.OutOrStdout()
+	pluginCmd.Stderr = cmd.ErrOrStderr()
+	pluginCmd.Env = env
+	pluginCmd.ExtraFiles = []*os.File{r}
+	pluginCmd.Cancel = func() error {
+		return pluginCmd.Process.Signal(os.Interrupt)
+	}
+	pluginCmd.WaitDelay = c.gracePeriod()
+
+	c.logger(cmd).Debugf("running plugin %s %q", p.Path, args)
+	if err := pluginCmd.Start(); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to start plugin %s: %w", p.Name, err)
+	}
+	// Write the snapshot while the plugin runs: it may not read it at all
+	go func() {
+		defer w.Close()
+		w.Write(content)
+	}()
+
+	if err := pluginCmd.Wait(); err != nil {
+		return fmt.Errorf("plugin %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// environ returns the environment of the command tree as KEY=VALUE strings.
+func (c *cli) environ() []string {
+	if c.opts.Env == nil {
+		return os.Environ()
+	}
+	env := make([]string, 0, len(c.opts.Env))
+	for key, value := range c.opts.Env {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+// envValue formats a resolved config value as an env variable value, the
+// list values being separated by commas.
+func envValue(value any) string {
+	switch v := value.(type) {
+	case []string, []any:
+		return strings.Join(cast.ToStringSlice(v), ",")
+	default:
+		return cast.ToString(v)
+	}
+}
+
+// newPluginCmd returns the plugin command and its subcommands.
+func newPluginCmd(c *cli) *cobra.Command {
+	pluginCmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Manage the external plugins found on PATH",
+		Long: `Plugins are executables named "cobravsviper-<name>" found on PATH. The command
+"cobravsviper <name>" runs the plugin when no built-in command <name> exists.
+
+The plugins receive the resolved root persistent config as COBRAVSVIPER_* env
+variables, and as a JSON snapshot on the file descriptor given in the
+COBRAVSVIPER_PLUGIN_CONFIG_FD env variable.`,
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the plugins found on PATH and their name collisions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plugins := c.FindPlugins(cmd.Root())
+			if plugins == nil {
+				plugins = []*Plugin{}
+			}
+			return c.renderE(cmd, plugins, output.Options{}, func() error {
+				if len(plugins) == 0 {
+					c.logger(cmd).Warn("no plugin found on PATH")
+					return nil
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "The following compatible plugins are available:")
+				fmt.Fprintln(cmd.OutOrStdout(), "")
+				for _, p := range plugins {
+					fmt.Fprintln(cmd.OutOrStdout(), p.Path)
+					for _, warning := range p.Warnings {
+						fmt.Fprintf(cmd.OutOrStdout(), "  - warning: %s\n", warning)
+						c.logger(cmd).Warn(warning)
+					}
+				}
+				return nil
+			})
+		},
+	}
+	addOutputFlag(listCmd, output.Formats)
+	pluginCmd.AddCommand(listCmd)
+
+	return pluginCmd
+}
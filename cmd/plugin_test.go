@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// TestFindPlugins checks the plugins are found on PATH with their name
+// collisions, and are only added to the tree when the first word is not a
+// built-in command.
+func TestFindPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by their PATHEXT extension on windows")
+	}
+	fs := afero.NewMemMapFs()
+	for file, mode := range map[string]os.FileMode{
+		"/a/cobravsviper-foo":     0o755,
+		"/a/cobravsviper-version": 0o755,
+		"/b/cobravsviper-foo":     0o755,
+		"/b/cobravsviper-bar":     0o755,
+		"/b/cobravsviper-baz.sh":  0o755,
+		"/b/cobravsviper-noexec":  0o644,
+		"/b/other":                0o755,
+	} {
+		if err := afero.WriteFile(fs, file, []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatalf("failed to write %s: %v", file, err)
+		}
+	}
+	c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: map[string]string{"PATH": "/a" + string(filepath.ListSeparator) + "/b"}, Fs: fs})
+	rootCmd := newRootCmd(c)
+
+	got := map[string][]string{}
+	for _, p := range c.FindPlugins(rootCmd) {
+		got[p.Path] = append([]string{p.Name}, p.Warnings...)
+	}
+	want := map[string][]string{
+		"/a/cobravsviper-foo":     {"foo"},
+		"/a/cobravsviper-version": {"version", `/a/cobravsviper-version is overshadowed by the built-in command "version"`},
+		"/b/cobravsviper-bar":     {"bar"},
+		"/b/cobravsviper-baz.sh":  {"baz.sh"},
+		"/b/cobravsviper-foo":     {"foo", "/b/cobravsviper-foo is overshadowed by a similarly named plugin: /a/cobravsviper-foo"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got plugins %v, expected %v", got, want)
+	}
+
+	// PATH is not scanned to run a built-in command
+	if _, err := c.prepareArgsE(rootCmd, []string{"version"}); err != nil {
+		t.Fatalf("prepareArgsE failed: %v", err)
+	}
+	if cmd, _, err := rootCmd.Find([]string{"foo"}); err == nil && cmd.Annotations[annotationPlugin] != "" {
+		t.Errorf("plugin foo added to the tree to run a built-in command")
+	}
+
+	// Only the plugins without collision are commands of the tree
+	if _, err := c.prepareArgsE(rootCmd, []string{"foo"}); err != nil {
+		t.Fatalf("prepareArgsE failed: %v", err)
+	}
+	for name, isPlugin := range map[string]bool{"foo": true, "bar": true, "version": false} {
+		cmd, _, err := rootCmd.Find([]string{name})
+		if err != nil || (cmd.Annotations[annotationPlugin] != "") != isPlugin {
+			t.Errorf("command %s is a plugin? %v, expected %v", name, err == nil && cmd.Annotations[annotationPlugin] != "", isPlugin)
+		}
+	}
+}
+
+// TestTrimPathExt checks only the PATHEXT extensions are removed from the
+// names of the plugins on Windows.
+func TestTrimPathExt(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"foo.exe", "foo"},
+		{"foo.CMD", "foo"},
+		{"foo.bar.bat", "foo.bar"},
+		{"foo.sh", ""},
+		{"foo", ""},
+	}
+	for _, tc := range cases {
+		if got := trimPathExt(tc.name, defaultPathExt); got != tc.want {
+			t.Errorf("trimPathExt(%q) = %q, expected %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestExecute_Plugin runs a plugin and checks it receives its arguments, the
+// root persistent config as env variables and the JSON snapshot, and that its
+// exit code is passed through.
+func TestExecute_Plugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugin is a shell script")
+	}
+	dir := t.TempDir()
+	// PATH only holds the plugin directory: use shell builtins only
+	script := `#!/bin/sh
+echo "args: $*"
+echo "env: $COBRAVSVIPER_ROOTPERSISTENTFLAG1"
+read -r snapshot <&$COBRAVSVIPER_PLUGIN_CONFIG_FD
+echo "$snapshot"
+exit 3
+`
+	if err := os.WriteFile(filepath.Join(dir, "cobravsviper-foo"), []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write the plugin: %v", err)
+	}
+	env := map[string]string{"PATH": dir, "COBRAVSVIPER_ROOTPERSISTENTFLAG2": "value from env", "COBRAVSVIPER_LOG_FILE_MAX_SIZE": "7"}
+
+	var out, errOut bytes.Buffer
+	result := Execute(context.Background(),
+		[]string{"--rootpersistentflag1", "value from cli", "--log-levels", "grp2cmd2=warn", "foo", "a", "--b", "-c"},
+		IOStreams{In: strings.NewReader(""), Out: &out, Err: &errOut}, env)
+
+	if result.ExitCode != 3 {
+		t.Errorf("got exit code %d (%v), expected the plugin exit code 3", result.ExitCode, result.Err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) < 3 || lines[0] != "args: a --b -c" || lines[1] != "env: value from cli" {
+		t.Fatalf("unexpected plugin output %q", out.String())
+	}
+	var snapshot PluginConfig
+	if err := json.Unmarshal([]byte(lines[2]), &snapshot); err != nil {
+		t.Fatalf("invalid JSON snapshot %q: %v (%s)", lines[2], err, errOut.String())
+	}
+	if snapshot.Settings["rootpersistentflag1"] != "value from cli" || snapshot.Settings["rootpersistentflag2"] != "value from env" {
+		t.Errorf("unexpected snapshot settings %v", snapshot.Settings)
+	}
+	// The values are typed as in the -o json output
+	if snapshot.Settings["log-file-max-size"] != float64(7) || snapshot.Settings["verbose"] != float64(0) ||
+		snapshot.Settings["log-caller"] != false || !reflect.DeepEqual(snapshot.Settings["log-levels"], []any{"grp2cmd2=warn"}) {
+		t.Errorf("unexpected snapshot setting types %v", snapshot.Settings)
+	}
+
+	// The plugin names are completed like the built-in commands
+	out.Reset()
+	Execute(context.Background(), []string{"__complete", "fo"}, IOStreams{In: strings.NewReader(""), Out: &out, Err: &errOut}, env)
+	if !strings.HasPrefix(out.String(), "foo\t") {
+		t.Errorf("expected the completion of the plugin foo, got %q", out.String())
+	}
+}
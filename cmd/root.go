@@ -4,31 +4,19 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/nicop311/cobravsviper/pkg/output"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var (
-	debug     bool
-	logFormat string
-	logLevel  string
-)
-
-var rootFlag1 string
-var rootFlag2 string
-var rootFlag3 string
-var rootFlag4 string
-
-var rootPersistentFlag1 string
-var rootPersistentFlag2 string
-var rootPersistentFlag3 string
-var rootPersistentFlag4 string
-var cfgFile string
-
 type ViperFlagsRoot struct {
 	CfgFile             string
 	RootFlag1           string `mapstructure:"rootflag1"`
@@ -41,53 +29,239 @@ type ViperFlagsRoot struct {
 	RootPersistentFlag4 string `mapstructure:"rootpersistentflag4"`
 
 	Debug     bool   `mapstructure:"debug"`
+	Verbose   int    `mapstructure:"verbose"`
+	Quiet     int    `mapstructure:"quiet"`
 	LogFormat string `mapstructure:"log-format"`
 	LogLevel  string `mapstructure:"log-level"`
+
+	LogTimestampFormat string `mapstructure:"log-timestamp-format"`
+	LogTimezone        string `mapstructure:"log-timezone"`
+	LogCaller          bool   `mapstructure:"log-caller"`
+
+	LogFieldNames []string `mapstructure:"log-field-names"`
+	LogLevels     []string `mapstructure:"log-levels"`
+
+	Color string `mapstructure:"color"`
+
+	LogFile            string        `mapstructure:"log-file"`
+	LogFileFormat      string        `mapstructure:"log-file-format"`
+	LogFileLevel       string        `mapstructure:"log-file-level"`
+	LogFileMaxSize     int           `mapstructure:"log-file-max-size"`
+	LogFileMaxAge      int           `mapstructure:"log-file-max-age"`
+	LogFileMaxBackups  int           `mapstructure:"log-file-max-backups"`
+	LogFileCompress    bool          `mapstructure:"log-file-compress"`
+	LogFileRotateEvery time.Duration `mapstructure:"log-file-rotate-every"`
+
+	ShutdownGracePeriod time.Duration `mapstructure:"shutdown-grace-period"`
+}
+
+// IOStreams holds the standard streams of a command tree. Logs are written to
+// Err.
+type IOStreams struct {
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+}
+
+// Options holds the dependencies injected into a command tree built by
+// NewRootCmd. The zero value uses the standard streams, the environment and
+// the filesystem of the process.
+type Options struct {
+	// IOStreams are the standard streams of the command tree.
+	IOStreams
+
+	// Env holds the environment variables of the command tree. If nil, the
+	// environment of the process is used.
+	Env map[string]string
+
+	// Fs is the filesystem the config and dotenv files are read from. If nil,
+	// the OS filesystem is used.
+	Fs afero.Fs
+
+	// Viper is the Viper instance of the command tree. If nil, a new instance
+	// is created.
+	Viper *viper.Viper
 }
 
-// Initialize the ViperConfig struct with all the root CLI flags bound to Viper env vars
-var vprFlgsRoot ViperFlagsRoot
+// cli holds the state of one command tree built by NewRootCmd. Two trees
+// never share any state.
+type cli struct {
+	opts Options
+	v    *viper.Viper
+	log  *logrus.Logger
 
-// rootCmd represents the base command when called without any subcommands
-var rootCmd = &cobra.Command{
-	Use:   "cobravsviper",
-	Short: "The ROOT command",
-	Long: `A longer description that spans multiple lines and likely contains
+	// dotEnv holds the variables loaded from the dotenv files
+	dotEnv map[string]string
+
+	// sources holds the config layer each bound config key is resolved from
+	sources map[string]Source
+
+	// initHooks and postRunHooks hold the hooks of each command (see OnInit
+	// and OnPostRun)
+	initHooks    map[*cobra.Command][]Hook
+	postRunHooks map[*cobra.Command][]Hook
+
+	// configTargets holds the config target of each command (see
+	// SetConfigTarget)
+	configTargets map[*cobra.Command]any
+
+	// constraints holds the constraints between the flags of each command
+	// (see MarkConfigMutuallyExclusive)
+	constraints map[*cobra.Command][]constraint
+
+	// logSinks are the destinations of the logs of the tree, and logLevels
+	// the log levels of the commands, set by initLogging. levelLoggers holds
+	// the logger of each level of logLevels, guarded by logMu (see logger)
+	logSinks     []*logSink
+	logLevels    map[*cobra.Command]logrus.Level
+	levelLoggers map[logrus.Level]*logrus.Logger
+	logMu        sync.Mutex
+	// logCaller is set to log the caller of the entries
+	logCaller bool
+	// logFile is the sink of --log-file, nil if not set. The trees of the
+	// lines of a shell share the sink of the shell (see newLineCLI)
+	logFile *logSink
+
+	// mu guards the shutdown hooks and grace period, read by Execute while
+	// the command may still be running
+	mu                  sync.Mutex
+	shutdownHooks       []shutdownHook
+	shutdownGracePeriod time.Duration
+
+	// root persistent flags needed before the config resolution
+	cfgFile           string
+	envFiles          []string
+	loadDefaultDotEnv bool
+
+	// configLoaded is set when the dotenv files and the config file are
+	// already loaded into the tree, e.g. by the shell command
+	configLoaded bool
+
+	// aliasConfigSources is set when the dotenv files and the config file
+	// are loaded to read the aliases (see loadAliases), to the configSources
+	// they were selected by
+	aliasConfigSources string
+
+	// Initialize the ViperConfig struct with all the root CLI flags bound to Viper env vars
+	vprFlgsRoot ViperFlagsRoot
+}
+
+// newCLI returns the state of a new command tree, filling the unset options
+// with the process defaults.
+func newCLI(opts Options) *cli {
+	if opts.In == nil {
+		opts.In = os.Stdin
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	if opts.Err == nil {
+		opts.Err = os.Stderr
+	}
+	opts.Out = &syncWriter{w: opts.Out}
+	opts.Err = &syncWriter{w: opts.Err}
+	if opts.Fs == nil {
+		opts.Fs = afero.NewOsFs()
+	}
+	if opts.Viper == nil {
+		opts.Viper = viper.New()
+	}
+	opts.Viper.SetFs(opts.Fs)
+
+	log := logrus.New()
+	log.SetOutput(opts.Err)
+
+	return &cli{
+		opts:    opts,
+		v:       opts.Viper,
+		log:     log,
+		dotEnv:  map[string]string{},
+		sources: map[string]Source{},
+
+		initHooks:    map[*cobra.Command][]Hook{},
+		postRunHooks: map[*cobra.Command][]Hook{},
+
+		configTargets: map[*cobra.Command]any{},
+		constraints:   map[*cobra.Command][]constraint{},
+
+		levelLoggers: map[logrus.Level]*logrus.Logger{},
+
+		shutdownGracePeriod: defaultShutdownGracePeriod,
+	}
+}
+
+// NewRootCmd builds a new command tree with the given options. Every call
+// returns an independent tree: flag values, Viper instance and logger are not
+// shared with any other tree.
+func NewRootCmd(opts Options) *cobra.Command {
+	return newRootCmd(newCLI(opts))
+}
+
+func newRootCmd(c *cli) *cobra.Command {
+	var (
+		debug     bool
+		logFormat string
+		logLevel  string
+	)
+
+	var rootFlag1 string
+	var rootFlag2 string
+	var rootFlag3 string
+	var rootFlag4 string
+
+	var rootPersistentFlag1 string
+	var rootPersistentFlag2 string
+	var rootPersistentFlag3 string
+	var rootPersistentFlag4 string
+
+	// rootCmd represents the base command when called without any subcommands
+	rootCmd := &cobra.Command{
+		Use:   "cobravsviper",
+		Short: "The ROOT command",
+		Long: `A longer description that spans multiple lines and likely contains
 examples and usage of using your application. For example:
 
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
-	// Uncomment the following line if your bare application
-	// has an action associated with it:
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("")
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("Root command called")
-
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootflag1: %s", vprFlgsRoot.RootFlag1)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootflag2: %s", vprFlgsRoot.RootFlag2)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootflag3: %s", vprFlgsRoot.RootFlag3)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootflag4: %s", vprFlgsRoot.RootFlag4)
-
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootpersistentflag1: %s", vprFlgsRoot.RootPersistentFlag1)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootpersistentflag2: %s", vprFlgsRoot.RootPersistentFlag2)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootpersistentflag3: %s", vprFlgsRoot.RootPersistentFlag3)
-		logrus.WithField("cobra-cmd", cmd.Use).Infof("rootpersistentflag4: %s", vprFlgsRoot.RootPersistentFlag4)
-	},
-}
+		// Run the init hooks of every command of the path, from the root
+		// command to the executed command, and the post-run hooks in reverse
+		// order. No other command defines a PersistentPreRunE or a
+		// PersistentPostRunE: they register hooks with c.OnInit and
+		// c.OnPostRun instead.
+		PersistentPreRunE:  c.runInitHooksE,
+		PersistentPostRunE: c.runPostRunHooksE,
+		// Uncomment the following line if your bare application
+		// has an action associated with it:
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.logger(cmd).Debug("Root command called")
 
-// Execute adds all child commands to the root command and sets flags appropriately.
-// This is called by main.main(). It only needs to happen once to the rootCmd.
-func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+			return c.renderE(cmd, c.commandResult(cmd), output.Options{}, func() error {
+				c.printFlag(cmd, "rootflag1", c.vprFlgsRoot.RootFlag1)
+				c.printFlag(cmd, "rootflag2", c.vprFlgsRoot.RootFlag2)
+				c.printFlag(cmd, "rootflag3", c.vprFlgsRoot.RootFlag3)
+				c.printFlag(cmd, "rootflag4", c.vprFlgsRoot.RootFlag4)
+
+				c.printFlag(cmd, "rootpersistentflag1", c.vprFlgsRoot.RootPersistentFlag1)
+				c.printFlag(cmd, "rootpersistentflag2", c.vprFlgsRoot.RootPersistentFlag2)
+				c.printFlag(cmd, "rootpersistentflag3", c.vprFlgsRoot.RootPersistentFlag3)
+				c.printFlag(cmd, "rootpersistentflag4", c.vprFlgsRoot.RootPersistentFlag4)
+				return nil
+			})
+		},
 	}
-}
+	// The root persistent flags configure the logger of the whole tree
+	c.SetConfigTarget(rootCmd, &c.vprFlgsRoot)
+	c.OnInit(rootCmd, func(cmd *cobra.Command, args []string) error {
+		return c.initLogging(cmd)
+	})
+	rootCmd.SetIn(c.opts.In)
+	rootCmd.SetOut(c.opts.Out)
+	rootCmd.SetErr(c.opts.Err)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &UsageError{Err: err}
+	})
 
-func init() {
-	// Ensure initConfig runs before anything else
-	cobra.OnInitialize(initConfig)
 	// Define command groups
 	group1 := &cobra.Group{
 		ID:    "group1",
@@ -109,19 +283,42 @@ func init() {
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Configuration File")
+	rootCmd.PersistentFlags().StringVar(&c.cfgFile, "config", "", "Configuration File")
+	rootCmd.PersistentFlags().StringArrayVar(&c.envFiles, "env-file", nil, "Dotenv file to load. Can be repeated, later files win. Real environment variables win over dotenv files. Corresponding environment variable: COBRAVSVIPER_ENV_FILE (list separated by the OS path list separator).")
+	rootCmd.PersistentFlags().BoolVar(&c.loadDefaultDotEnv, "dotenv", false, "Load the .env file of the current working directory before the --env-file files. Corresponding environment variable: COBRAVSVIPER_DOTENV.")
 
 	// logging level
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Set logrus.SetLevel to \"debug\". This is equivalent to using --log-level=debug. Flags --log-level and --debug flag are mutually exclusive. Corresponding environment variable: K8S_KMS_PLUGIN_DEBUG.")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Set logrus.SetLevel. Possible values: trace, debug, info, warning, error, fatal and panic. Flags --log-level and --debug flag are mutually exclusive. Corresponding environment variable: K8S_KMS_PLUGIN_LOG_LEVEL.")
-	rootCmd.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"trace", "debug", "info", "warning", "error", "fatal", "panic"}, cobra.ShellCompDirectiveNoFileComp
-	})
-	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Logrus log output format. Possible values: text, json. Corresponding environment variable: K8S_KMS_PLUGIN_LOG_FORMAT")
-	rootCmd.RegisterFlagCompletionFunc("log-format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp
-	})
-	rootCmd.MarkFlagsMutuallyExclusive("log-level", "debug")
+	rootCmd.RegisterFlagCompletionFunc("log-level", completeLogLevels)
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Logrus log output format. Possible values: "+strings.Join(logFormatNames(), ", ")+". Corresponding environment variable: K8S_KMS_PLUGIN_LOG_FORMAT")
+	rootCmd.RegisterFlagCompletionFunc("log-format", completeLogFormats)
+	rootCmd.PersistentFlags().StringSlice("log-field-names", nil, "Rename fields of the logs, as field=name pairs, e.g. msg=message,cobra-cmd=command. Fields: "+strings.Join(logFields, ", ")+". Each format has its own default names.")
+	rootCmd.PersistentFlags().CountP("verbose", "v", "Increase the level of the logs: -v for debug, -vv for trace, -vvv for trace with the caller of the logs. Conflicts with --log-level, --debug and --quiet, from any source. Corresponding environment variable: COBRAVSVIPER_VERBOSE (a count).")
+	rootCmd.PersistentFlags().CountP("quiet", "q", "Decrease the level of the logs: -q for warning, -qq for error, -qqq for fatal. Conflicts with --log-level, --debug and --verbose, from any source. Corresponding environment variable: COBRAVSVIPER_QUIET (a count).")
+	c.MarkConfigMutuallyExclusive(rootCmd, verbosityFlags...)
+	rootCmd.PersistentFlags().String("log-timestamp-format", "", "Format of the timestamps of the logs: rfc3339, rfc3339nano, datetime, stampmilli, none, or a Go time layout such as 15:04:05.000. Defaults to rfc3339nano, without timestamp for the text logs of the console.")
+	rootCmd.PersistentFlags().String("log-timezone", "", "Time zone of the timestamps of the logs, e.g. UTC or Europe/Paris. Defaults to the local time zone.")
+	rootCmd.PersistentFlags().Bool("log-caller", false, "Log the file and line of the code logging each entry.")
+	rootCmd.PersistentFlags().StringSlice("log-levels", nil, "Log levels of commands, as command=level pairs, e.g. grp2cmd2=debug,grp2cmd2.sub221=warn. A command logs at the level of its closest command with a level, the --log-level level otherwise. Also set with the log-level key of the config section of the command, or its COBRAVSVIPER_<COMMAND PATH>_LOG_LEVEL env variable.")
+	rootCmd.RegisterFlagCompletionFunc("log-levels", completeCommandLogLevels)
+
+	rootCmd.PersistentFlags().String("color", colorAuto, "When to color the output: "+strings.Join(colorModes, ", ")+". With auto, the output is colored if it is a terminal, unless NO_COLOR is set; CLICOLOR_FORCE forces it. Corresponding environment variable: COBRAVSVIPER_COLOR.")
+	rootCmd.RegisterFlagCompletionFunc("color", completeColorModes)
+
+	// log file, logged to in addition to the console
+	rootCmd.PersistentFlags().String("log-file", "", "Also write the logs to this file, rotated by size and optionally by age. Corresponding environment variable: COBRAVSVIPER_LOG_FILE.")
+	rootCmd.PersistentFlags().String("log-file-format", defaultLogFileFormat, "Format of the logs written to --log-file. Same values as --log-format.")
+	rootCmd.RegisterFlagCompletionFunc("log-file-format", completeLogFormats)
+	rootCmd.PersistentFlags().String("log-file-level", "", "Level of the logs written to --log-file, independent of the console level. Same values as --log-level. Defaults to the console level.")
+	rootCmd.RegisterFlagCompletionFunc("log-file-level", completeLogLevels)
+	rootCmd.PersistentFlags().Int("log-file-max-size", defaultLogFileMaxSize, "Size in megabytes at which --log-file is rotated.")
+	rootCmd.PersistentFlags().Int("log-file-max-age", defaultLogFileMaxAge, "Number of days to keep the rotated log files. 0 keeps them regardless of their age.")
+	rootCmd.PersistentFlags().Int("log-file-max-backups", defaultLogFileMaxBackups, "Number of rotated log files to keep. 0 keeps them all.")
+	rootCmd.PersistentFlags().Bool("log-file-compress", false, "Compress the rotated log files with gzip.")
+	rootCmd.PersistentFlags().Duration("log-file-rotate-every", 0, "Also rotate --log-file at this interval, e.g. 24h. 0 disables the age-based rotation.")
+
+	rootCmd.PersistentFlags().Duration("shutdown-grace-period", defaultShutdownGracePeriod, "Maximum time to wait for the command and the shutdown hooks to stop after an interrupt (SIGINT or SIGTERM).")
 
 	rootCmd.PersistentFlags().StringVar(&rootPersistentFlag1, "rootpersistentflag1", "value from default", "persistent root flag 1")
 	rootCmd.PersistentFlags().StringVar(&rootPersistentFlag2, "rootpersistentflag2", "value from default", "persistent root flag 2")
@@ -132,48 +329,47 @@ func init() {
 	rootCmd.Flags().StringVar(&rootFlag2, "rootflag2", "value from default", "root flag 2")
 	rootCmd.Flags().StringVar(&rootFlag3, "rootflag3", "value from default", "root flag 3")
 	rootCmd.Flags().StringVar(&rootFlag4, "rootflag4", "value from default", "root flag 4")
+	addOutputFlag(rootCmd, output.Formats)
+
+	rootCmd.AddCommand(
+		newGrp1cmd1Cmd(c),
+		newGrp1cmd2Cmd(c),
+		newGrp2cmd1Cmd(c),
+		newGrp2cmd2Cmd(c),
+		newVersionCmd(c),
+		newPluginCmd(c),
+		newShellCmd(c),
+		newConfigCmd(c),
+	)
+
+	return rootCmd
 }
 
-func initConfig() {
-
-	ReadViperConfigE(viper.GetViper(), rootCmd)
-
-	InitViperSubCmdE(viper.GetViper(), rootCmd, &vprFlgsRoot)
-
-	// Set logs format
-	switch vprFlgsRoot.LogFormat {
-	case "json":
-		logrus.SetFormatter(&logrus.JSONFormatter{
-			PrettyPrint: false,
-		})
-	case "text":
-		logrus.SetFormatter(&logrus.TextFormatter{
-			ForceColors:      true,
-			DisableTimestamp: true,
-		})
-	default:
-		logrus.WithError(fmt.Errorf("logrus unknown output format")).Error("unknown log format")
+// loadConfigE loads the config layers shared by all the commands of the tree:
+// the dotenv files, then the config file. It does nothing when they are
+// already loaded.
+func (c *cli) loadConfigE(rootCmd *cobra.Command) error {
+	if c.configLoaded {
+		return nil
 	}
-	logrus.Debugf("logrus output format is set to: %s", vprFlgsRoot.LogFormat)
-
-	// Initialize logrus log level and log format for all cobra commands and subcommands.
-	debugFlagIsUsed := rootCmd.Flags().Lookup("debug").Changed
-
-	switch {
-	case debugFlagIsUsed:
-		// harcode that the --debug flags set logrus level to debug
-		logrus.SetLevel(logrus.DebugLevel)
-	default:
-		// get the log level from viper which is bind to the cobra flag --log-level
-		level, err := logrus.ParseLevel(vprFlgsRoot.LogLevel)
-		if err != nil {
-			logrus.WithError(err).Error("unknown log level")
-		}
-		logrus.SetLevel(level)
+	// Reuse the layers loaded for the aliases, unless the command line parsed
+	// by cobra selects other files
+	if c.aliasConfigSources != "" && c.aliasConfigSources == configSources(rootCmd) {
+		c.configLoaded = true
+		return nil
 	}
-	logrus.Debugf("logrus log-level is set to: %s", logrus.GetLevel())
 
-	// Debugging: Show all loaded settings
-	logrus.Tracef("Viper settings: %+v", viper.AllSettings())
+	// Load the dotenv files first: they may also set COBRAVSVIPER_CONFIG
+	values, err := c.LoadDotEnvFilesE(rootCmd)
+	if err != nil {
+		c.log.WithError(err).Error("failed to load dotenv files")
+		return err
+	}
+	c.dotEnv = values
 
+	if err := c.ReadViperConfigE(rootCmd); err != nil {
+		c.log.WithError(err).Error("failed to read config file")
+		return err
+	}
+	return nil
 }
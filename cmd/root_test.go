@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+const testConfigFile = "/etc/cobravsviper/cobravsviper.conf.yaml"
+
+// newTestFs returns an in-memory filesystem holding the config file content.
+func newTestFs(t *testing.T, content string) afero.Fs {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, testConfigFile, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return fs
+}
+
+// executeTree builds a new command tree with opts, runs it with args and
+// returns the lines written to stdout.
+func executeTree(t *testing.T, opts Options, args ...string) (stdout string, lines map[string]bool, err error) {
+	t.Helper()
+	var out bytes.Buffer
+	opts.Out = &out
+	opts.Err = &bytes.Buffer{}
+
+	rootCmd := NewRootCmd(opts)
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+
+	lines = map[string]bool{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		lines[line] = true
+	}
+	return out.String(), lines, err
+}
+
+// TestNewRootCmd_IndependentTrees runs two trees built with different options
+// in parallel and checks that none of them sees the flags, environment or
+// config file of the other.
+func TestNewRootCmd_IndependentTrees(t *testing.T) {
+	trees := []struct {
+		name string
+		opts Options
+		args []string
+		want []string
+	}{
+		{
+			name: "env and config",
+			opts: Options{
+				Env: map[string]string{
+					"COBRAVSVIPER_CONFIG":                           testConfigFile,
+					"COBRAVSVIPER_GRP2CMD2_SUB221_SUB221FLAG2":      "value from env",
+					"COBRAVSVIPER_GRP2CMD2_GRP2CMD2PERSISTENTFLAG2": "value from env",
+				},
+				Fs: newTestFs(t, `
+cobravsviper:
+  rootpersistentflag3: value from config
+  grp2cmd2:
+    sub221:
+      sub221flag3: value from config
+`),
+			},
+			args: []string{"grp2cmd2", "sub221", "--sub221flag1", "value from cli"},
+			want: []string{
+				"sub221flag1: value from cli (flag)",
+				"sub221flag2: value from env (env)",
+				"sub221flag3: value from config (config)",
+				"sub221flag4: value from default (default)",
+				"grp2cmd2persistentflag2: value from env (env)",
+				"rootpersistentflag3: value from config (config)",
+			},
+		},
+		{
+			name: "defaults only",
+			opts: Options{Env: map[string]string{}, Fs: afero.NewMemMapFs()},
+			args: []string{"grp2cmd2", "sub221"},
+			want: []string{
+				"sub221flag1: value from default (default)",
+				"sub221flag2: value from default (default)",
+				"sub221flag3: value from default (default)",
+				"grp2cmd2persistentflag2: value from default (default)",
+				"rootpersistentflag3: value from default (default)",
+			},
+		},
+	}
+
+	for _, tree := range trees {
+		t.Run(tree.name, func(t *testing.T) {
+			t.Parallel()
+			for i := 0; i < 3; i++ {
+				_, lines, err := executeTree(t, tree.opts, tree.args...)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				for _, want := range tree.want {
+					if !lines[want] {
+						t.Errorf("missing line %q in %v", want, lines)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestNewRootCmd_Streams checks the command output is written to the injected
+// stdout.
+func TestNewRootCmd_Streams(t *testing.T) {
+	stdout, _, err := executeTree(t, Options{Env: map[string]string{}, Fs: afero.NewMemMapFs()}, "grp1cmd1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout != "grp1cmd1 called\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "grp1cmd1 called\n")
+	}
+}
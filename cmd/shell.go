@@ -0,0 +1,357 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peterh/liner"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+const (
+	// shellPrompt is the prompt of the shell command.
+	shellPrompt = "cobravsviper> "
+
+	// shellHistoryFile is the name of the history file of the shell command,
+	// in the state directory (see stateDir).
+	shellHistoryFile = "shell_history"
+
+	// shellHistoryLimit is the maximum number of lines kept in the history.
+	shellHistoryLimit = 1000
+)
+
+// newShellCmd returns the shell command
+func newShellCmd(c *cli) *cobra.Command {
+	// shellCmd represents the shell command
+	shellCmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Run the commands of the tree from an interactive prompt",
+		Long: `Start a prompt reading one command line of the tree per line, e.g.
+"grp2cmd2 sub221 --sub221flag1=foo", until "exit", "quit" or end of input.
+
+The dotenv files and the config file are loaded once, when the shell starts,
+and the root persistent flags given to the shell apply to every line. Each line
+then runs in a new command tree, resolving the config of its commands from
+scratch: the flags of a line never leak into the next one. Ctrl-C interrupts
+the running line only.
+
+The lines are completed with the tab key like in a shell with the completion
+script installed, and the history is saved in
+$XDG_STATE_HOME/cobravsviper/shell_history (~/.local/state by default).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.runShellE(cmd)
+		},
+	}
+
+	return shellCmd
+}
+
+// lineReader reads the lines of the shell. It is implemented by liner.State
+// on a terminal.
+type lineReader interface {
+	Prompt(prompt string) (string, error)
+	AppendHistory(item string)
+	ReadHistory(r io.Reader) (int, error)
+	WriteHistory(w io.Writer) (int, error)
+	Close() error
+}
+
+// newLineReader returns a line editor with completion on a terminal, or a
+// plain line reader of the input of the tree otherwise, e.g. for a script
+// piped to the shell.
+func (c *cli) newLineReader(complete liner.WordCompleter) lineReader {
+	if c.opts.In == os.Stdin && term.IsTerminal(int(os.Stdin.Fd())) {
+		line := liner.NewLiner()
+		line.SetCtrlCAborts(true)
+		line.SetTabCompletionStyle(liner.TabPrints)
+		line.SetWordCompleter(complete)
+		return line
+	}
+	return &plainLineReader{scanner: bufio.NewScanner(c.opts.In)}
+}
+
+// plainLineReader is the lineReader of a non-interactive input: no prompt is
+// printed and no completion is offered.
+type plainLineReader struct {
+	scanner *bufio.Scanner
+	history []string
+}
+
+func (r *plainLineReader) Prompt(prompt string) (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *plainLineReader) AppendHistory(item string) {
+	r.history = append(r.history, item)
+	if len(r.history) > shellHistoryLimit {
+		r.history = r.history[len(r.history)-shellHistoryLimit:]
+	}
+}
+
+func (r *plainLineReader) ReadHistory(in io.Reader) (int, error) {
+	scanner := bufio.NewScanner(in)
+	n := 0
+	for scanner.Scan() {
+		r.AppendHistory(scanner.Text())
+		n++
+	}
+	return n, scanner.Err()
+}
+
+func (r *plainLineReader) WriteHistory(out io.Writer) (int, error) {
+	for i, item := range r.history {
+		if _, err := fmt.Fprintln(out, item); err != nil {
+			return i, err
+		}
+	}
+	return len(r.history), nil
+}
+
+func (r *plainLineReader) Close() error {
+	return nil
+}
+
+// shellSession holds what the lines of a shell share: the config loaded when
+// the shell started and the root persistent flags given to the shell.
+type shellSession struct {
+	parent     *cli
+	configFile string
+	config     []byte
+	rootArgs   []string
+}
+
+// runShellE reads the lines of the input of the tree and runs each of them in
+// a new command tree, until "exit", "quit" or the end of the input.
+func (c *cli) runShellE(cmd *cobra.Command) error {
+	log := c.logger(cmd)
+
+	s := &shellSession{parent: c, configFile: c.v.ConfigFileUsed()}
+	if s.configFile != "" {
+		config, err := afero.ReadFile(c.opts.Fs, s.configFile)
+		if err != nil {
+			return &ConfigNotFoundError{File: s.configFile, Err: err}
+		}
+		s.config = config
+	}
+	// The config layers are loaded once: the flags locating them are not
+	// passed to the lines. The slice flags are passed once per item, their
+	// String() is "[a,b]".
+	cmd.Root().PersistentFlags().Visit(func(f *pflag.Flag) {
+		if isConfigLocationFlag(f.Name) {
+			return
+		}
+		if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+			for _, item := range sliceValue.GetSlice() {
+				s.rootArgs = append(s.rootArgs, "--"+f.Name+"="+item)
+			}
+			return
+		}
+		s.rootArgs = append(s.rootArgs, "--"+f.Name+"="+f.Value.String())
+	})
+
+	reader := c.newLineReader(func(line string, pos int) (string, []string, string) {
+		return s.complete(cmd, line, pos)
+	})
+	defer reader.Close()
+
+	historyFile, err := c.stateFile(shellHistoryFile)
+	if err != nil {
+		log.WithError(err).Warn("shell history disabled")
+	} else if f, err := c.opts.Fs.Open(historyFile); err == nil {
+		reader.ReadHistory(f)
+		f.Close()
+	}
+
+	for cmd.Context().Err() == nil {
+		line, err := reader.Prompt(shellPrompt)
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the shell input: %w", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		reader.AppendHistory(line)
+		if line == "exit" || line == "quit" {
+			break
+		}
+		s.run(cmd, line)
+	}
+
+	if historyFile != "" {
+		if err := c.saveHistoryE(reader, historyFile); err != nil {
+			log.WithError(err).Warn("shell history not saved")
+		}
+	}
+	return cmd.Context().Err()
+}
+
+// run runs the command line in a new command tree. The errors are printed by
+// the tree and do not stop the shell.
+func (s *shellSession) run(cmd *cobra.Command, line string) {
+	log := s.parent.logger(cmd)
+
+	words, err := splitShellWords(line)
+	if err != nil {
+		log.WithError(err).Error("invalid command line")
+		return
+	}
+	if words[0] == cmd.Name() {
+		log.Error("already in the shell")
+		return
+	}
+
+	// SIGINT interrupts the line only, not the shell
+	ctx, cancel := context.WithCancelCause(cmd.Context())
+	defer cancel(nil)
+	defer onInterrupt(cmd.Context(), cancel)()
+
+	c := s.newLineCLI(s.parent.opts.IOStreams)
+	result := c.execute(ctx, newRootCmd(c), append(append([]string{}, s.rootArgs...), words...))
+	if result.Err != nil {
+		log.WithError(result.Err).Debugf("command exited with code %d", result.ExitCode)
+	}
+}
+
+// newLineCLI returns the state of the command tree of a line, with the config
+// loaded by the shell. The line logs to the --log-file sink of the shell
+// instead of opening the file again.
+func (s *shellSession) newLineCLI(streams IOStreams) *cli {
+	c := newCLI(Options{IOStreams: streams, Env: s.parent.opts.Env, Fs: s.parent.opts.Fs, Viper: viper.New()})
+	c.configLoaded = true
+	c.logFile = s.parent.logFile
+	for key, value := range s.parent.dotEnv {
+		c.dotEnv[key] = value
+	}
+	if s.configFile != "" {
+		c.v.SetConfigFile(s.configFile)
+		if err := c.v.ReadConfig(bytes.NewReader(s.config)); err != nil {
+			// The shell already read the config successfully
+			c.log.WithError(err).Error("failed to read config file")
+		}
+	}
+	return c
+}
+
+// complete completes the word of line ending at pos with the completions of
+// the tree, as given by the hidden completion command of cobra. It returns the
+// text before the word, the candidates replacing it and the text after pos.
+func (s *shellSession) complete(cmd *cobra.Command, line string, pos int) (string, []string, string) {
+	head, tail := line[:pos], line[pos:]
+	words, err := splitShellWords(head)
+	if err != nil {
+		return head, nil, tail
+	}
+	toComplete := ""
+	if head != "" && !strings.ContainsAny(head[len(head)-1:], " \t") {
+		toComplete = words[len(words)-1]
+		words = words[:len(words)-1]
+		head = head[:strings.LastIndexAny(head, " \t")+1]
+	}
+
+	var out bytes.Buffer
+	c := s.newLineCLI(IOStreams{In: s.parent.opts.In, Out: &out, Err: io.Discard})
+	args := append([]string{cobra.ShellCompNoDescRequestCmd}, s.rootArgs...)
+	args = append(append(args, words...), toComplete)
+	c.execute(cmd.Context(), newRootCmd(c), args)
+
+	var candidates []string
+	for _, candidate := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(candidate, ":") {
+			// The completion directive ends the candidates
+			break
+		}
+		// Like the shells, keep the candidates matching the word only: the
+		// completion functions may return all their values
+		if candidate != "" && strings.HasPrefix(candidate, toComplete) {
+			candidates = append(candidates, candidate+" ")
+		}
+	}
+	return head, candidates, tail
+}
+
+// stateDir returns the directory of the state files of the tree:
+// $XDG_STATE_HOME/cobravsviper, or ~/.local/state/cobravsviper.
+func (c *cli) stateDir() (string, error) {
+	if dir, ok := c.getenv("XDG_STATE_HOME"); ok && dir != "" {
+		return filepath.Join(dir, "cobravsviper"), nil
+	}
+	home, err := c.homeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "cobravsviper"), nil
+}
+
+// stateFile returns the path of the state file name.
+func (c *cli) stateFile(name string) (string, error) {
+	dir, err := c.stateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// saveHistoryE writes the history of reader to the file historyFile, creating
+// its directory if needed.
+func (c *cli) saveHistoryE(reader lineReader, historyFile string) error {
+	if err := c.opts.Fs.MkdirAll(filepath.Dir(historyFile), 0o700); err != nil {
+		return err
+	}
+	f, err := c.opts.Fs.OpenFile(historyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := reader.WriteHistory(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// TestShell checks each line of the shell runs in a new command tree with the
+// config and the root persistent flags of the shell, and the history is saved
+// in the state directory.
+func TestShell(t *testing.T) {
+	fs := newTestFs(t, `
+cobravsviper:
+  grp2cmd2:
+    sub221:
+      sub221flag1: value from config
+`)
+	script := strings.Join([]string{
+		"grp2cmd2 sub221 --sub221flag2=bar",
+		"",
+		"grp2cmd2 sub221",
+		"grp2cmd2 sub221 'unterminated",
+		"shell",
+		"exit",
+		"grp1cmd1",
+	}, "\n")
+
+	var out, logs bytes.Buffer
+	c := newCLI(Options{
+		IOStreams: IOStreams{In: strings.NewReader(script), Out: &out, Err: &logs},
+		Env:       map[string]string{"HOME": "/home/user", "COBRAVSVIPER_CONFIG": testConfigFile},
+		Fs:        fs,
+	})
+	result := c.execute(context.Background(), newRootCmd(c), []string{"--log-format=json", "shell"})
+	if result.ExitCode != ExitOK {
+		t.Fatalf("got %+v, expected exit code %d", result, ExitOK)
+	}
+
+	if got := strings.Count(out.String(), "sub221flag1: value from config (config)\n"); got != 2 {
+		t.Errorf("got %d lines with the value of the config, expected 2 in %s", got, out.String())
+	}
+	if got := strings.Count(out.String(), "sub221flag2: bar (flag)\n"); got != 1 {
+		t.Errorf("got %d lines with the flag value, expected 1: the flags must not leak into the next line in %s", got, out.String())
+	}
+	output := logs.String()
+	for _, want := range []string{"unterminated single quote", "already in the shell"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in %s", want, output)
+		}
+	}
+	if strings.Contains(out.String(), "grp1cmd1 called") {
+		t.Errorf("the line after exit ran: %s", out.String())
+	}
+
+	history, err := afero.ReadFile(fs, "/home/user/.local/state/cobravsviper/shell_history")
+	if err != nil {
+		t.Fatalf("failed to read the history: %v", err)
+	}
+	want := "grp2cmd2 sub221 --sub221flag2=bar\ngrp2cmd2 sub221\ngrp2cmd2 sub221 'unterminated\nshell\nexit\n"
+	if string(history) != want {
+		t.Errorf("got history %q, expected %q", history, want)
+	}
+}
+
+// TestShell_SliceFlags checks the slice root persistent flags given to the
+// shell are passed to each line item by item.
+func TestShell_SliceFlags(t *testing.T) {
+	var out, logs bytes.Buffer
+	c := newCLI(Options{
+		IOStreams: IOStreams{In: strings.NewReader("grp2cmd2 -o 'jsonpath={.command}{\"\\n\"}'\ngrp2cmd2 sub221\n"), Out: &out, Err: &logs},
+		Env:       map[string]string{"HOME": "/home/user", "COBRAVSVIPER_CONFIG": testConfigFile},
+		Fs:        newTestFs(t, ""),
+	})
+	args := []string{"--log-format=json", "--log-levels", "grp2cmd2=warn", "--log-levels", "grp2cmd2.sub221=debug", "--log-field-names", "msg=message,cobra-cmd=command", "shell"}
+	result := c.execute(context.Background(), newRootCmd(c), args)
+	if result.ExitCode != ExitOK {
+		t.Fatalf("got %+v, expected exit code %d", result, ExitOK)
+	}
+
+	if !strings.HasPrefix(out.String(), "grp2cmd2\n") {
+		t.Errorf("got output %q, expected the result of the first line", out.String())
+	}
+	output := logs.String()
+	if !strings.Contains(output, `"message":"sub221 subcommand called"`) {
+		t.Errorf("missing the renamed message of the debug log of sub221 in %s", output)
+	}
+	if strings.Contains(output, `"level":"error"`) {
+		t.Errorf("a line failed: %s", output)
+	}
+}
+
+// TestShell_Complete checks the lines are completed with the completions of
+// the command tree, including the flag completion functions.
+func TestShell_Complete(t *testing.T) {
+	c := newCLI(Options{IOStreams: IOStreams{In: strings.NewReader(""), Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: map[string]string{}, Fs: afero.NewMemMapFs()})
+	s := &shellSession{parent: c}
+	cmd := &cobra.Command{Use: "shell"}
+	cmd.SetContext(context.Background())
+
+	cases := []struct {
+		line     string
+		wantHead string
+		want     []string
+	}{
+		{"grp2cmd2 su", "grp2cmd2 ", []string{"sub221 ", "sub222 "}},
+		{"grp2cmd2 sub221 --log-le", "grp2cmd2 sub221 ", []string{"--log-level ", "--log-levels "}},
+		{"--log-level d", "--log-level ", []string{"debug "}},
+		{"version --log-format ", "version --log-format ", []string{"text ", "logfmt ", "json ", "ecs ", "otel "}},
+		{"grp2cmd2 'unterminated", "grp2cmd2 'unterminated", nil},
+	}
+
+	for _, tc := range cases {
+		head, got, tail := s.complete(cmd, tc.line+" tail", len(tc.line))
+		if head != tc.wantHead || !reflect.DeepEqual(got, tc.want) || tail != " tail" {
+			t.Errorf("complete(%q) = %q, %q, %q, expected %q, %q, %q", tc.line, head, got, tail, tc.wantHead, tc.want, " tail")
+		}
+	}
+}
+
+// TestShell_LogFile checks the lines log to the --log-file sink of the shell
+// instead of opening the file again.
+func TestShell_LogFile(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "cobravsviper.log")
+	c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: map[string]string{}, Fs: newTestFs(t, "")})
+	if result := c.execute(context.Background(), newRootCmd(c), []string{"--log-file", logFile, "version"}); result.ExitCode != ExitOK {
+		t.Fatalf("got %+v, expected exit code %d", result, ExitOK)
+	}
+
+	s := &shellSession{parent: c}
+	line := s.newLineCLI(c.opts.IOStreams)
+	if result := line.execute(context.Background(), newRootCmd(line), []string{"--log-file", logFile, "version"}); result.ExitCode != ExitOK {
+		t.Fatalf("got %+v, expected exit code %d", result, ExitOK)
+	}
+	if line.logFile == nil || line.logFile != c.logFile {
+		t.Errorf("the line logs to %+v, expected the sink of the shell %+v", line.logFile, c.logFile)
+	}
+}
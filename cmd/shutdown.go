@@ -0,0 +1,261 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultShutdownGracePeriod is the default value of the
+// --shutdown-grace-period flag.
+const defaultShutdownGracePeriod = 10 * time.Second
+
+// ErrInterrupted is returned by Execute when the context of the command is
+// canceled, e.g. on SIGINT or SIGTERM.
+var ErrInterrupted = errors.New("interrupted")
+
+// NotifyContext returns a copy of parent canceled on SIGINT or SIGTERM, like
+// signal.NotifyContext, to give to Execute. While the shell command runs a
+// line, SIGINT cancels the line only and the shell reads the next one (see
+// onInterrupt). The stop function cancels the context and stops relaying the
+// signals.
+func NotifyContext(parent context.Context) (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	in := &interrupter{cancels: []*context.CancelCauseFunc{&cancel}}
+	ctx = context.WithValue(ctx, interrupterKey{}, in)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				in.interrupt(sig)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(context.Canceled)
+	}
+}
+
+// interrupter relays the signals received by the context of NotifyContext.
+// cancels holds the cancel function of the context, then the ones registered
+// by onInterrupt, the last one canceled on SIGINT.
+type interrupter struct {
+	mu      sync.Mutex
+	cancels []*context.CancelCauseFunc
+}
+
+// interrupterKey is the key of the interrupter in the context of
+// NotifyContext.
+type interrupterKey struct{}
+
+// interrupt cancels the context of NotifyContext on SIGTERM, and the last
+// registered context on SIGINT.
+func (in *interrupter) interrupt(sig os.Signal) {
+	in.mu.Lock()
+	cancel := in.cancels[len(in.cancels)-1]
+	if sig != os.Interrupt {
+		cancel = in.cancels[0]
+	}
+	in.mu.Unlock()
+	(*cancel)(fmt.Errorf("%s signal received", sig))
+}
+
+// onInterrupt makes SIGINT call cancel instead of canceling ctx, or the
+// context registered before, until the returned function is called. It does
+// nothing if ctx does not come from NotifyContext.
+func onInterrupt(ctx context.Context, cancel context.CancelCauseFunc) (restore func()) {
+	in, ok := ctx.Value(interrupterKey{}).(*interrupter)
+	if !ok {
+		return func() {}
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.cancels = append(in.cancels, &cancel)
+	return func() {
+		in.mu.Lock()
+		defer in.mu.Unlock()
+		for i, registered := range in.cancels {
+			if registered == &cancel {
+				in.cancels = append(in.cancels[:i], in.cancels[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// ShutdownHook releases a resource when the command tree stops. The context
+// expires at the end of the shutdown grace period.
+type ShutdownHook func(ctx context.Context) error
+
+// shutdownHook is a named ShutdownHook.
+type shutdownHook struct {
+	name string
+	hook ShutdownHook
+}
+
+// OnShutdown registers a hook run when the command tree stops, whether the
+// command succeeded, failed or was interrupted. Hooks run in the reverse order
+// of their registration, like deferred calls.
+func (c *cli) OnShutdown(name string, hook ShutdownHook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shutdownHooks = append(c.shutdownHooks, shutdownHook{name: name, hook: hook})
+}
+
+// setShutdownGracePeriod sets the grace period of the shutdown, ignoring the
+// non-positive values.
+func (c *cli) setShutdownGracePeriod(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shutdownGracePeriod = d
+}
+
+// ShutdownE runs the shutdown hooks in the reverse order of their
+// registration. A hook error does not prevent the next hooks from running,
+// but the hooks left when ctx expires are skipped.
+//
+// It returns the errors of the hooks joined with errors.Join.
+func (c *cli) ShutdownE(ctx context.Context) error {
+	c.mu.Lock()
+	hooks := c.shutdownHooks
+	c.shutdownHooks = nil
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %s skipped: %w", hooks[i].name, err))
+			continue
+		}
+		c.log.Tracef("running shutdown hook %s", hooks[i].name)
+		if err := hooks[i].hook(ctx); err != nil {
+			c.log.WithError(err).Errorf("shutdown hook %s failed", hooks[i].name)
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", hooks[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// execute runs the command tree with the context ctx and the arguments args,
+// then runs the shutdown hooks.
+//
+// When ctx is canceled, the command is given the shutdown grace period to
+// return, then the shutdown hooks run within what is left of it. The error
+// returned is then wrapped in ErrInterrupted.
+func (c *cli) execute(ctx context.Context, rootCmd *cobra.Command, args []string) Result {
+	args, err := c.prepareArgsE(rootCmd, args)
+	if err != nil {
+		rootCmd.PrintErrln(rootCmd.ErrPrefix(), err.Error())
+		return Result{ExitCode: ExitCode(err), Err: err}
+	}
+	rootCmd.SetArgs(args)
+
+	type outcome struct {
+		cmd *cobra.Command
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		cmd, err := rootCmd.ExecuteContextC(ctx)
+		done <- outcome{cmd: cmd, err: err}
+	}()
+
+	var o outcome
+	returned := false
+	select {
+	case o = <-done:
+		returned = true
+		if ctx.Err() == nil {
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.gracePeriod())
+			defer cancel()
+			err := errors.Join(usageError(rootCmd, o.cmd, args, o.err), c.ShutdownE(shutdownCtx))
+			return Result{ExitCode: ExitCode(err), Err: err}
+		}
+		// The command returned because of the interrupt: shut down as such
+	case <-ctx.Done():
+	}
+
+	gracePeriod := c.gracePeriod()
+	c.log.WithField("cause", context.Cause(ctx)).WithField("grace-period", gracePeriod).Warn("interrupted, shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), gracePeriod)
+	defer cancel()
+
+	cmdErr := o.err
+	if !returned {
+		select {
+		case o = <-done:
+			cmdErr = o.err
+		case <-shutdownCtx.Done():
+			cmdErr = fmt.Errorf("the command did not stop within the shutdown grace period of %s", gracePeriod)
+		}
+	}
+	if errors.Is(cmdErr, context.Canceled) {
+		// The command stopped on the interrupt as expected
+		cmdErr = nil
+	}
+
+	err = errors.Join(fmt.Errorf("%w: %v", ErrInterrupted, context.Cause(ctx)), cmdErr, c.ShutdownE(shutdownCtx))
+	c.log.WithError(err).Warn("shutdown complete")
+	return Result{ExitCode: ExitCode(err), Err: err}
+}
+
+// gracePeriod returns the shutdown grace period.
+func (c *cli) gracePeriod() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.shutdownGracePeriod
+}
+
+// syncWriter serializes the writes to w. The standard streams of a command
+// tree are written by the command and, on interrupt, by the shutdown running
+// concurrently.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
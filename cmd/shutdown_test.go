@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// newShutdownTestTree returns a command tree with a "block" command that
+// registers the shutdown hooks "first" and "second", signals it started, then
+// waits for the interrupt if honorCtx is set, or forever otherwise. The names
+// of the hooks are recorded in their run order.
+func newShutdownTestTree(honorCtx bool) (c *cli, rootCmd *cobra.Command, started chan struct{}, ran func() []string, logs *bytes.Buffer) {
+	logs = &bytes.Buffer{}
+	c = newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: logs}, Env: map[string]string{}, Fs: afero.NewMemMapFs()})
+	rootCmd = newRootCmd(c)
+
+	var mu sync.Mutex
+	var names []string
+	record := func(name string) ShutdownHook {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			names = append(names, name)
+			return nil
+		}
+	}
+	ran = func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), names...)
+	}
+
+	started = make(chan struct{})
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "block",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.OnShutdown("first", record("first"))
+			c.OnShutdown("second", record("second"))
+			close(started)
+			if honorCtx {
+				<-cmd.Context().Done()
+				return cmd.Context().Err()
+			}
+			select {}
+		},
+	})
+	return c, rootCmd, started, ran, logs
+}
+
+// TestExecute_Interrupt checks an interrupted command stops, runs the
+// shutdown hooks in the reverse order of their registration and returns the
+// interrupted exit code.
+func TestExecute_Interrupt(t *testing.T) {
+	c, rootCmd, started, ran, logs := newShutdownTestTree(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	result := c.execute(ctx, rootCmd, []string{"block"})
+
+	if result.ExitCode != ExitInterrupted || !errors.Is(result.Err, ErrInterrupted) {
+		t.Errorf("got %+v, expected exit code %d and ErrInterrupted", result, ExitInterrupted)
+	}
+	if got, want := ran(), []string{"second", "first"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("shutdown hooks ran in order %v, expected %v", got, want)
+	}
+	if !strings.Contains(logs.String(), "interrupted, shutting down") {
+		t.Errorf("missing shutdown log in %q", logs.String())
+	}
+}
+
+// TestExecute_InterruptGracePeriod checks a command ignoring the interrupt is
+// abandoned at the end of the shutdown grace period.
+func TestExecute_InterruptGracePeriod(t *testing.T) {
+	c, rootCmd, started, ran, _ := newShutdownTestTree(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	begin := time.Now()
+	result := c.execute(ctx, rootCmd, []string{"--shutdown-grace-period", "50ms", "block"})
+
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("Execute returned after %s, expected the grace period of 50ms", elapsed)
+	}
+	if result.ExitCode != ExitInterrupted || result.Err == nil || !strings.Contains(result.Err.Error(), "grace period of 50ms") {
+		t.Errorf("got %+v, expected exit code %d and a grace period error", result, ExitInterrupted)
+	}
+	if got := ran(); len(got) != 0 {
+		t.Errorf("shutdown hooks %v ran after the grace period", got)
+	}
+}
+
+// TestExecute_ShutdownOnSuccess checks the shutdown hooks also run when the
+// command succeeds.
+func TestExecute_ShutdownOnSuccess(t *testing.T) {
+	c := newCLI(Options{IOStreams: IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}, Env: map[string]string{}, Fs: afero.NewMemMapFs()})
+	rootCmd := newRootCmd(c)
+	var ran bool
+	c.OnShutdown("hook", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	result := c.execute(context.Background(), rootCmd, []string{"grp1cmd1"})
+	if result.ExitCode != ExitOK || !ran {
+		t.Errorf("got %+v, hook ran? %v, expected exit code %d and the hook to run", result, ran, ExitOK)
+	}
+}
+
+// TestNotifyContext_Interrupt checks SIGINT cancels the last context
+// registered with onInterrupt only, and SIGTERM cancels the context of
+// NotifyContext.
+func TestNotifyContext_Interrupt(t *testing.T) {
+	ctx, stop := NotifyContext(context.Background())
+	defer stop()
+	in := ctx.Value(interrupterKey{}).(*interrupter)
+
+	lineCtx, cancel := context.WithCancelCause(ctx)
+	restore := onInterrupt(ctx, cancel)
+	in.interrupt(os.Interrupt)
+	if lineCtx.Err() == nil || ctx.Err() != nil {
+		t.Fatalf("SIGINT canceled the line %v and the context %v, expected the line only", lineCtx.Err(), ctx.Err())
+	}
+	restore()
+
+	lineCtx, cancel = context.WithCancelCause(ctx)
+	defer onInterrupt(ctx, cancel)()
+	in.interrupt(syscall.SIGTERM)
+	if ctx.Err() == nil || lineCtx.Err() == nil {
+		t.Fatalf("SIGTERM canceled the context %v and the line %v, expected both", ctx.Err(), lineCtx.Err())
+	}
+	if cause := context.Cause(ctx); cause == nil || !strings.Contains(cause.Error(), "signal received") {
+		t.Errorf("got cause %v, expected the signal", cause)
+	}
+}
+
+// TestNotifyContext_InterruptRestored checks SIGINT cancels the context of
+// NotifyContext once the registered contexts are restored.
+func TestNotifyContext_InterruptRestored(t *testing.T) {
+	ctx, stop := NotifyContext(context.Background())
+	defer stop()
+	in := ctx.Value(interrupterKey{}).(*interrupter)
+
+	_, cancel := context.WithCancelCause(ctx)
+	onInterrupt(ctx, cancel)()
+	in.interrupt(os.Interrupt)
+	if ctx.Err() == nil {
+		t.Errorf("SIGINT did not cancel the context")
+	}
+}
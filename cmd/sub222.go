@@ -1,32 +1,34 @@
 /*
 Copyright © 2025 NAME HERE <EMAIL ADDRESS>
-
 */
 package cmd
 
 import (
 	"fmt"
 
+	"github.com/nicop311/cobravsviper/pkg/output"
 	"github.com/spf13/cobra"
 )
 
-// sub222Cmd represents the sub222 command
-var sub222Cmd = &cobra.Command{
-	Use:   "sub222",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
+// newSub222Cmd returns the sub222 command
+func newSub222Cmd(c *cli) *cobra.Command {
+	// sub222Cmd represents the sub222 command
+	sub222Cmd := &cobra.Command{
+		Use:   "sub222",
+		Short: "A brief description of your command",
+		Long: `A longer description that spans multiple lines and likely contains examples
 and usage of using your command. For example:
 
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("sub222 called")
-	},
-}
-
-func init() {
-	grp2cmd2Cmd.AddCommand(sub222Cmd)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.renderE(cmd, c.commandResult(cmd), output.Options{}, func() error {
+				fmt.Fprintln(cmd.OutOrStdout(), "sub222 called")
+				return nil
+			})
+		},
+	}
 
 	// Here you will define your flags and configuration settings.
 
@@ -37,4 +39,8 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// sub222Cmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	addOutputFlag(sub222Cmd, output.Formats)
+
+	return sub222Cmd
 }
@@ -4,71 +4,110 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/nicop311/cobravsviper/pkg/output"
 	"github.com/nicop311/cobravsviper/pkg/version"
 
-	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
-// CLI options pflags names
-var outputFormat string // One of 'yaml' or 'json'.
-
-// prettyPrintVersion defined by the user with flag --pretty
-var prettyPrintVersion bool
-
 // ViperFlagsVersion defines a struct to hold all the configuration values and use viper.Unmarshal
 // to populate it:
 type ViperFlagsVersion struct {
 	OutputFormat       string `mapstructure:"output"`
 	PrettyPrintVersion bool   `mapstructure:"pretty"`
+	Check              string `mapstructure:"check"`
+	AllowSnapshot      bool   `mapstructure:"allow-snapshot"`
+	AllowDirty         bool   `mapstructure:"allow-dirty"`
 }
 
-var vprFlgsVersion ViperFlagsVersion
+// newVersionCmd returns the version command
+func newVersionCmd(c *cli) *cobra.Command {
+	// prettyPrintVersion defined by the user with flag --pretty
+	var prettyPrintVersion bool
 
-// versionCmd represents the version command
-var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Print the version information",
-	Long: `Print the version information with various level of details
+	var vprFlgsVersion ViperFlagsVersion
+
+	// versionCmd represents the version command
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version information",
+		Long: `Print the version information with various level of details
 including information of the build and git repository metadata.
 
 Examples:
   # print the version information with git repository details as a one liner
   # JSON string.
-  cobravsviper version -o json --pretty=false`,
-	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if err := InitViperSubCmdE(viper.GetViper(), cmd, &vprFlgsVersion); err != nil {
-			logrus.WithField("cobra-cmd", cmd.Use).WithError(err).Error("Error initializing Viper")
-			return err
-		}
-		return nil
-	},
-	Run: func(cmd *cobra.Command, args []string) {
-		// Output version info
-		logrus.WithField("cobra-cmd", cmd.Use).Debug("version subcommand called")
+  cobravsviper version -o json --pretty=false
 
-		if !version.IsPopulated() {
-			logrus.WithField("cobra-cmd", cmd.Use).Warn("build & git metadata are missing")
-		}
+  # print the git commit of the build
+  cobravsviper version -o jsonpath='{.cobravsviper.gitCommitIdShort}'
 
-		fmt.Fprintln(cmd.OutOrStdout(), version.VersionOutputToString(vprFlgsVersion.OutputFormat, vprFlgsVersion.PrettyPrintVersion))
-	},
-}
+  # exit with 6 unless the version is at least 1.4 and below 2.0
+  cobravsviper version --check ">= 1.4, < 2.0"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Output version info
+			c.logger(cmd).Debug("version subcommand called")
+
+			if !version.IsPopulated() {
+				c.logger(cmd).Warn("build & git metadata are missing")
+			}
+
+			// A version that is not a semantic version still has its build
+			// and git metadata
+			log := c.logger(cmd)
+			data, err := version.NewVersionDataWithLogger(log)
+			if err != nil {
+				log.WithError(err).Warn("version is not a semantic version")
+			}
+			if vprFlgsVersion.Check != "" {
+				return c.checkVersionE(cmd, data, vprFlgsVersion.Check, vprFlgsVersion.AllowSnapshot, vprFlgsVersion.AllowDirty)
+			}
 
-func init() {
-	// rootCmd is the parent command
-	rootCmd.AddCommand(versionCmd)
+			details := version.VersionDetails{VersionData: data}
+			opts := output.Options{Compact: !vprFlgsVersion.PrettyPrintVersion, Formats: version.Formats}
+			return c.renderE(cmd, details, opts, func() error {
+				fmt.Fprintln(cmd.OutOrStdout(), version.VersionOutputToStringWithLogger(log, "", vprFlgsVersion.PrettyPrintVersion))
+				return nil
+			})
+		},
+	}
+
+	c.SetConfigTarget(versionCmd, &vprFlgsVersion)
 
 	// Here you will define your flags and configuration settings.
-	versionCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Format of the version output. One of 'yaml' or 'json'.")
-	versionCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"yaml", "json"}, cobra.ShellCompDirectiveNoFileComp
-	})
+	addOutputFlag(versionCmd, version.Formats)
 	versionCmd.Flags().BoolVarP(&prettyPrintVersion, "pretty", "P", true, "Activate pretty print output for JSON.")
+	versionCmd.Flags().String("check", "", "Check the version satisfies constraints, e.g. \">= 1.4, < 2.0\", instead of printing it. Exits with 6 if it does not.")
+	versionCmd.Flags().Bool("allow-snapshot", false, "With --check, let the snapshot builds (not built from a semantic version tag) pass without being checked. They fail by default.")
+	versionCmd.Flags().Bool("allow-dirty", false, "With --check, check the builds from a dirty git tree like the others. They fail by default.")
 	versionCmd.RegisterFlagCompletionFunc("pretty", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{"true", "false"}, cobra.ShellCompDirectiveNoFileComp
 	})
+
+	return versionCmd
+}
+
+// checkVersionE checks versionData against constraints with version.Check,
+// logging the result. A version not satisfying them is a *version.CheckError,
+// invalid constraints are a *ValidationError of the check key. An allowed
+// snapshot build is not checked: it is logged as such.
+func (c *cli) checkVersionE(cmd *cobra.Command, versionData version.VersionData, constraints string, allowSnapshot, allowDirty bool) error {
+	err := version.Check(versionData, constraints, allowSnapshot, allowDirty)
+	var checkErr *version.CheckError
+	switch {
+	case errors.As(err, &checkErr):
+		return err
+	case err != nil:
+		setting, _ := c.flagSetting(cmd, "check")
+		return &ValidationError{Key: setting.Key, Source: setting.Source, Err: err}
+	}
+	if versionData.ClosestTag == "" {
+		c.logger(cmd).Warnf("version %q is a snapshot build, check against %s skipped", versionData.Version, constraints)
+		return nil
+	}
+	c.logger(cmd).Infof("version %s satisfies %s", versionData.Version, constraints)
+	return nil
 }
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nicop311/cobravsviper/pkg/version"
+)
+
+// TestVersionCheck checks version --check exits with ExitVersionCheck when the
+// version does not satisfy the constraints, set with the flag or the env,
+// with ExitValidation when they are invalid, and prints nothing. An allowed
+// snapshot build is logged as not checked.
+func TestVersionCheck(t *testing.T) {
+	rawGitDescribe, gitDirtyStr := version.RawGitDescribe, version.GitDirtyStr
+	t.Cleanup(func() {
+		version.RawGitDescribe, version.GitDirtyStr = rawGitDescribe, gitDirtyStr
+	})
+
+	cases := []struct {
+		name     string
+		version  string
+		dirty    string
+		env      map[string]string
+		args     []string
+		exitCode int
+		log      string
+	}{
+		{"satisfied", "v1.4.2", "false", nil, []string{"--check", ">= 1.4, < 2.0"}, ExitOK, "version v1.4.2 satisfies >= 1.4, < 2.0"},
+		{"not satisfied", "v2.1.0", "false", nil, []string{"--check", ">= 1.4, < 2.0"}, ExitVersionCheck, ""},
+		{"env", "v1.3.0", "false", map[string]string{"COBRAVSVIPER_VERSION_CHECK": ">= 1.4"}, nil, ExitVersionCheck, ""},
+		{"snapshot", "abc123", "false", nil, []string{"--check", ">= 1.4"}, ExitVersionCheck, ""},
+		{"empty snapshot allowed", "", "false", nil, []string{"--check", ">= 0.0.0", "--allow-snapshot"}, ExitOK, `version \"\" is a snapshot build, check against >= 0.0.0 skipped`},
+		{"snapshot allowed", "abc123", "false", nil, []string{"--check", ">= 1.4", "--allow-snapshot"}, ExitOK, `version \"abc123\" is a snapshot build, check against >= 1.4 skipped`},
+		{"dirty", "v1.4.2", "true", nil, []string{"--check", ">= 1.4"}, ExitVersionCheck, ""},
+		{"dirty with snapshot allowed", "v1.4.2", "true", nil, []string{"--check", ">= 1.4", "--allow-snapshot"}, ExitVersionCheck, ""},
+		{"dirty allowed", "v1.4.2", "true", map[string]string{"COBRAVSVIPER_VERSION_ALLOW_DIRTY": "true"}, []string{"--check", ">= 1.4"}, ExitOK, "version v1.4.2 satisfies >= 1.4"},
+		{"prerelease", "v1.4.0-rc.1", "false", nil, []string{"--check", ">= 1.4"}, ExitVersionCheck, ""},
+		{"invalid constraints", "v1.4.2", "false", nil, []string{"--check", "1.4+"}, ExitValidation, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			version.RawGitDescribe, version.GitDirtyStr = tc.version, tc.dirty
+			env := map[string]string{"COBRAVSVIPER_CONFIG": testConfigFile}
+			for k, v := range tc.env {
+				env[k] = v
+			}
+			var out, logs bytes.Buffer
+			c := newCLI(Options{IOStreams: IOStreams{Out: &out, Err: &logs}, Env: env, Fs: newTestFs(t, "")})
+			result := c.execute(context.Background(), newRootCmd(c), append([]string{"version"}, tc.args...))
+			if result.ExitCode != tc.exitCode {
+				t.Errorf("got %+v, expected exit code %d", result, tc.exitCode)
+			}
+			if out.Len() > 0 {
+				t.Errorf("expected no output, got %q", out.String())
+			}
+			if tc.log != "" && !strings.Contains(logs.String(), tc.log) {
+				t.Errorf("missing %q in the logs:\n%s", tc.log, logs.String())
+			}
+		})
+	}
+}
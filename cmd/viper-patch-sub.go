@@ -23,31 +23,32 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 
-	"github.com/mitchellh/go-homedir"
-
-	"github.com/sirupsen/logrus"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 // UnmarshalSubMergedE is a temporary fix to a flaw in viper.Sub("section") that ignores the flag/env/default/override
-// priority chain when using viper.Unmarshal(). It merges the subsection of the config file back into the Viper config
-// layer (without overriding) and then calls viper.Unmarshal() to take into account the flag/env/default/override
-// priority chain.
+// priority chain when using viper.Unmarshal(). It resolves every key of the whole Viper instance with the
+// flag/env/default/override priority chain, and then only decodes the keys of the subsection into the target.
 //
 // Parameters:
 //   - v: the viper instance that contains the configuration
-//   - section: the subsection of the config file to merge
+//   - section: the dot separated path of the subsection to unmarshal (e.g. "cobravsviper.grp2cmd2.sub221")
 //   - target: the struct to unmarshal the merged configuration into
 //
-// It will return an error if:
-//   - the subsection does not exist in the config file
-//   - the merge into the Viper config layer fails
+// It will return an error if the decoding of the subsection into the target fails. When a value
+// cannot be decoded into the type of its target field, the error is a *ValidationError holding
+// the full key path of the value.
 //
 // The purpose of UnmarshalSubMergedE is to temporarily fix a flaw in viper.Sub("section") from here
 // https://github.com/spf13/viper/blob/9568cfcfd660a1c1c6c762f335ae79f370488417/viper.go#L764
@@ -65,83 +66,210 @@ import (
 // will not take into account the flag/env/default/override priority chain, since the viper.Sub()
 // instance only sees the config file data for that subsection.
 //
-// UnmarshalSubMergedE fixes this issue by merging the config file data for the subsection into the
-// Viper config layer, so that viper.Unmarshal() will use the flag/env/default/override priority chain.
+// UnmarshalSubMergedE fixes this issue by using viper.AllSettings(), which resolves each key (including the
+// keys of the bound flags and env variables) with the priority chain before building the nested settings map.
+// The flags and env variables must therefore be bound to their full key path (see InitViperSubCmdE).
 //
 // TODO: make a pull request to viper to fix this flaw.
 func UnmarshalSubMergedE(v *viper.Viper, section string, target any) error {
-	// 1. Skip if no config file is loaded at all
-	if v.ConfigFileUsed() == "" {
-		logrus.Trace("UnmarshalSubMerged: no config file loaded")
-		return v.Unmarshal(target) // only env, flags, defaults
+	// 1. Resolve all the keys with the priority chain:
+	// flags > env > config > defaults
+	settings := v.AllSettings()
+
+	// 2. Extract the subsection of the resolved settings
+	sub := settings
+	for _, key := range strings.Split(section, ".") {
+		next, ok := sub[key].(map[string]any)
+		if !ok {
+			// No subsection found, nothing is bound nor configured for this section
+			sub = map[string]any{}
+			break
+		}
+		sub = next
+	}
+
+	// 3. Decode the subsection like viper.Unmarshal() does
+	decoder, err := newSectionDecoder(target)
+	if err != nil {
+		return fmt.Errorf("failed to create decoder for section '%s': %w", section, err)
+	}
+	if err := decoder.Decode(sub); err != nil {
+		// 4. Find the key holding the invalid value by decoding the keys one by one
+		if key, keyErr := invalidKey(sub, target); keyErr != nil {
+			return &ValidationError{Key: section + "." + key, Err: keyErr}
+		}
+		return fmt.Errorf("failed to decode config section '%s': %w", section, err)
+	}
+	return nil
+}
+
+// newSectionDecoder returns a decoder behaving like the one of viper.Unmarshal().
+func newSectionDecoder(target any) (*mapstructure.Decoder, error) {
+	return mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           target,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+}
+
+// invalidKey decodes each key of the section alone into a new value of the
+// target type and returns the first key, in alphabetical order, that fails to
+// decode with its error.
+func invalidKey(sub map[string]any, target any) (string, error) {
+	targetType := reflect.TypeOf(target)
+	if targetType.Kind() != reflect.Pointer {
+		return "", nil
 	}
 
-	// 2. Extract the subsection of the config file
-	sub := v.GetStringMap(section)
-	if len(sub) == 0 {
-		// No subsection found, fallback to flags/env/default
-		logrus.Tracef("UnmarshalSubMerged: no config found for section '%s'", section)
-		return v.Unmarshal(target)
+	keys := make([]string, 0, len(sub))
+	for key := range sub {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	// 3. Merge section into Viper's config layer (not override!)
-	if err := v.MergeConfigMap(sub); err != nil {
-		logrus.WithError(err).Errorf("UnmarshalSubMerged: failed to merge config section '%s'", section)
-		return fmt.Errorf("failed to merge config section '%s': %w", section, err)
+	for _, key := range keys {
+		decoder, err := newSectionDecoder(reflect.New(targetType.Elem()).Interface())
+		if err != nil {
+			return "", nil
+		}
+		if err := decoder.Decode(map[string]any{key: sub[key]}); err != nil {
+			return key, err
+		}
 	}
+	return "", nil
+}
+
+// SectionPath returns the config file section of a cobra command: the full
+// command path from the root command, each command separated by a dot.
+//
+// Example: "cobravsviper grp2cmd2 sub221" gives "cobravsviper.grp2cmd2.sub221".
+func SectionPath(cobraCmd *cobra.Command) string {
+	// cobra.CommandPath returns the full path to the command, including all parent commands, each command separated by 1 space.
+	// TODO: allow cobra.CommandPath to get new separator like ".".
+	// See https://github.com/spf13/cobra/blob/40b5bc1437a564fc795d388b23835e84f54cd1d1/command.go#L1460
+	return strings.ReplaceAll(cobraCmd.CommandPath(), " ", ".")
+}
+
+// SectionEnvPrefix returns the env variable prefix of a cobra command: its
+// section path in uppercase snake case.
+//
+// Example: "cobravsviper.grp2cmd2.zu-lu-sub221" gives "COBRAVSVIPER_GRP2CMD2_ZU_LU_SUB221".
+func SectionEnvPrefix(cobraCmd *cobra.Command) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(SectionPath(cobraCmd)))
+}
 
-	// 4. Now unmarshal with proper priority:
-	// flags > env > merged config > defaults
-	return v.Unmarshal(target)
+// persistentFlagOwner returns the ancestor of a cobra command that declares
+// the inherited persistent flag flagName, or the command itself if no ancestor
+// declares it.
+func persistentFlagOwner(cobraCmd *cobra.Command, flagName string) *cobra.Command {
+	for c := cobraCmd.Parent(); c != nil; c = c.Parent() {
+		if c.PersistentFlags().Lookup(flagName) != nil {
+			return c
+		}
+	}
+	return cobraCmd
 }
 
+// Source is the config layer a resolved config key comes from.
+type Source string
 
-// InitViperSubCmdE initializes Viper for a specific Cobra subcommand. 
-// It sets up the environment variable prefix using the full command path
-// of the subcommand, with each command path segment separated by an underscore.
-// It then binds the subcommand-specific flags to Viper. Finally, it merges
-// the configuration from the subcommand's section in the config file into
-// Viper's configuration, allowing it to respect the usual priority chain of
-// flags > env variables > config file > defaults.
+// The config layers, from the highest to the lowest priority.
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceDotEnv  Source = "dotenv"
+	SourceConfig  Source = "config"
+	SourceDefault Source = "default"
+)
+
+// InitViperSubCmdE initializes Viper for a specific Cobra subcommand.
+// Each flag of the subcommand is resolved against the section of the command
+// that defines it: a local flag against the subcommand section, an inherited
+// persistent flag against the section of the ancestor that declares it.
+//
+// For every flag, it binds the key "<section>.<flag>" to the cobra flag and to
+// the env variable "<SECTION_ENV_PREFIX>_<FLAG>", where the section is the
+// full command path of the owning command with each command path segment
+// separated by a dot (see SectionPath and SectionEnvPrefix). Finally, it
+// unmarshals the subcommand's section into the target, respecting the usual
+// priority chain of flags > env variables > dotenv files > config file > defaults.
+//
+// Because the keys are fully qualified, the result does not depend on which
+// descendant runs nor on the order in which the commands are initialized.
+//
+// The env variables are looked up in the environment of the command tree (see
+// Options.Env), then in the variables loaded from the dotenv files.
 //
 // Parameters:
-//   - v: the Viper instance for managing configuration.
 //   - cobraCmd: the Cobra command representing the subcommand.
-//   - target: the structure to unmarshal the final configuration into.
+//   - target: the structure to unmarshal the final configuration into, or nil
+//     to only bind the flags.
+//
+// The config layer each key is resolved from is recorded, so that a
+// *ValidationError returned by UnmarshalSubMergedE reports the Source of the
+// invalid value.
 //
-// Returns an error if there is a failure in binding flags or unmarshalling 
+// Returns an error if there is a failure in binding flags or unmarshalling
 // the configuration.
-func InitViperSubCmdE(v *viper.Viper, cobraCmd *cobra.Command, target any) error {
-	// the name of the cobra subcommand is the "section" of the config file
-	// in this situation we suppose the cobra command correspond to a first level command. But if it is a second or third or greater level subcommand, we need the section to represent all the parent name. How can we get the fulle path to root command ?
-	logrus.WithField("cobra-cmd", cobraCmd.Use).Trace("cobra command path: " + cobraCmd.CommandPath())
+func (c *cli) InitViperSubCmdE(cobraCmd *cobra.Command, target any) error {
+	sectionPath := SectionPath(cobraCmd)
+	c.logger(cobraCmd).Tracef("section path: %s", sectionPath)
 
-	// cobra.CommandPath returns the full path to the command, including all parent commands, each command separated by 1 space.
-	// TODO: allow cobra.CommandPath to get new separator like ".".
-	// See https://github.com/spf13/cobra/blob/40b5bc1437a564fc795d388b23835e84f54cd1d1/command.go#L1460
-	sectionPath := strings.ReplaceAll(cobraCmd.CommandPath(), " ", ".")
-	logrus.WithField("cobra-cmd", cobraCmd.Use).Tracef("section path: %s", sectionPath)
-
-	// modify viper env prefix with the current cobra subcommand name
-	sectionEnvPrefix := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(sectionPath)) // for the env prefix, this should be uppercase snake case and replace dot . with underscore
-	// concat the previous viper env prefix with the current cobra subcommand name
-	logrus.WithField("cobra-cmd", cobraCmd.Use).Trace("new viper env prefix: " + sectionEnvPrefix)
-	v.SetEnvPrefix(sectionEnvPrefix)
-	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_")) // Converts flags to ENV format
-	v.AutomaticEnv()                                   // Enables automatic binding
-
-	// Bind subcommand-specific cobra flags to viper
-	err := v.BindPFlags(cobraCmd.Flags())
-	if err != nil {
-		logrus.WithField("cobra-cmd", cobraCmd.Use).Errorf("error binding flags: %v", err)
+	// Bind every cobra flag to the section of its owning command
+	var errs []error
+	bind := func(owner *cobra.Command, f *pflag.Flag) {
+		key := SectionPath(owner) + "." + f.Name
+		envName := envVarName(SectionEnvPrefix(owner), f.Name)
+		c.logger(cobraCmd).Tracef("bind flag %s to key %s and env %s", f.Name, key, envName)
+
+		if err := c.v.BindPFlag(key, f); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		// The env variable, real or loaded from a dotenv file, only applies
+		// when the flag is not set on the CLI: flags > env > dotenv > config > defaults
+		if f.Changed {
+			c.sources[key] = SourceFlag
+			return
+		}
+		if value, ok := c.getenv(envName); ok {
+			c.v.Set(key, value)
+			c.sources[key] = SourceEnv
+		} else if value, ok := c.dotEnv[envName]; ok {
+			c.v.Set(key, value)
+			c.sources[key] = SourceDotEnv
+		} else if c.v.InConfig(key) {
+			c.sources[key] = SourceConfig
+		} else {
+			c.sources[key] = SourceDefault
+		}
+	}
+	cobraCmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		bind(cobraCmd, f)
+	})
+	cobraCmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+		bind(persistentFlagOwner(cobraCmd, f.Name), f)
+	})
+	if err := errors.Join(errs...); err != nil {
+		c.logger(cobraCmd).Errorf("error binding flags: %v", err)
 		return fmt.Errorf("error binding flags: %w", err)
 	}
 
 	// Load config values for this subcommand
-	err = UnmarshalSubMergedE(v, sectionPath, &target)
+	if target == nil {
+		return nil
+	}
+	err := UnmarshalSubMergedE(c.v, sectionPath, target)
 	if err != nil {
-		logrus.WithField("cobra-cmd", cobraCmd.Use).Fatalf("failed to unmarshal version config: %v", err)
-		return fmt.Errorf("failed to unmarshal version config: %w", err)
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			validationErr.Source = c.sources[validationErr.Key]
+		}
+		c.logger(cobraCmd).Errorf("failed to unmarshal config: %v", err)
+		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
 	return nil
@@ -151,45 +279,70 @@ func InitViperSubCmdE(v *viper.Viper, cobraCmd *cobra.Command, target any) error
 //
 // If the "-c" or "--config" flag is set, it reads from the file specified by
 // that flag. If that flag is not set, it looks for an environment variable
-// named COBRAVSVIPER_CONFIG and reads the file specified by that variable.
+// named COBRAVSVIPER_CONFIG (real or loaded from a dotenv file) and reads the
+// file specified by that variable.
 // If neither the flag nor the environment variable is set, it looks for a
 // file named "cobravsviper.conf.yaml" in the following places, in order:
 // - The user's home directory (e.g. ~/.config/cobravsviper.conf.yaml)
 // - The .config directory under the user's home directory (e.g. ~/.config/cobravsviper.conf.yaml)
 //
-// If a config file is not found, it logs a trace error and continues with
-// cobra's default values. Otherwise, it reads in the config file and returns
-// an error if there was a problem doing so.
-func ReadViperConfigE(v *viper.Viper, cmd *cobra.Command) error {
+// The config file is read from the filesystem of the command tree (see Options.Fs).
+//
+// If a config file is not found in the default locations, it logs a trace
+// error and continues with cobra's default values. Otherwise, it reads in the
+// config file and returns a *ConfigNotFoundError if the file set with the flag
+// or the environment variable does not exist, or a *ConfigParseError if the
+// file cannot be parsed.
+func (c *cli) ReadViperConfigE(cmd *cobra.Command) error {
 	// use a configuration file parsed by viper
-	if cmd.Flags().Lookup("config").Changed && cfgFile != "" {
-		logrus.Tracef("Case config file from the flag: %s", cfgFile)
-		viper.SetConfigFile(cfgFile)
-	} else if envVar, ok := os.LookupEnv("COBRAVSVIPER_CONFIG"); ok {
-		logrus.Tracef("Case config file from the environment variable: %s", envVar)
-		viper.SetConfigFile(envVar)
+	var file string
+	if cmd.PersistentFlags().Lookup("config").Changed && c.cfgFile != "" {
+		c.log.Tracef("Case config file from the flag: %s", c.cfgFile)
+		file = c.cfgFile
+		c.v.SetConfigFile(file)
+	} else if envVar, ok := c.lookupEnv("COBRAVSVIPER_CONFIG"); ok {
+		c.log.Tracef("Case config file from the environment variable: %s", envVar)
+		file = envVar
+		c.v.SetConfigFile(file)
 	} else {
-		logrus.Tracef("Case config file from default location")
+		c.log.Tracef("Case config file from default location")
 		// Find home directory.
-		home, err := homedir.Dir()
+		home, err := c.homeDir()
 		if err != nil {
 			return fmt.Errorf("failed to find home directory: %w", err)
 		}
 
-		viper.SetConfigName("cobravsviper.conf") // name of config file (viper needs no file extension)
+		c.v.SetConfigName("cobravsviper.conf") // name of config file (viper needs no file extension)
 		// TODO: consider using the rootCmd.Flags().Lookup("config").DefValue for viper.SetConfigName
 		// logrus.Infof("default config filename %s", rootCmd.Flags().Lookup("config").DefValue)
-		logrus.Tracef("Search config in .config directory %s with name cobravsviper.conf.yaml (without extension).", home)
-		viper.AddConfigPath(home)
-		logrus.Tracef("Search config in home directory %s with name cobravsviper.conf.yaml (without extension).", filepath.Join(home, ".config/cobravsviper"))
-		viper.AddConfigPath(filepath.Join(home, ".config/cobravsviper"))
+		c.log.Tracef("Search config in .config directory %s with name cobravsviper.conf.yaml (without extension).", home)
+		c.v.AddConfigPath(home)
+		c.log.Tracef("Search config in home directory %s with name cobravsviper.conf.yaml (without extension).", filepath.Join(home, ".config/cobravsviper"))
+		c.v.AddConfigPath(filepath.Join(home, ".config/cobravsviper"))
+	}
+
+	// The file set with the flag or the env variable must exist. Check it
+	// before viper, which reports a file without extension as an unsupported
+	// config type even when it does not exist.
+	if file != "" {
+		if _, err := c.opts.Fs.Stat(file); errors.Is(err, fs.ErrNotExist) {
+			return &ConfigNotFoundError{File: file, Err: err}
+		}
 	}
 
 	// If a config file is not found, log a trace error. Otherwise, read it in.
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			logrus.Trace("No config file found; continue with cobra default values")
-		} else {
+	if err := c.v.ReadInConfig(); err != nil {
+		var parseErr viper.ConfigParseError
+		var unsupportedErr viper.UnsupportedConfigError
+		switch {
+		case errors.As(err, &viper.ConfigFileNotFoundError{}):
+			c.log.Trace("No config file found; continue with cobra default values")
+		case errors.Is(err, fs.ErrNotExist):
+			// The config file set with the flag or the env variable does not exist
+			return &ConfigNotFoundError{File: c.v.ConfigFileUsed(), Err: err}
+		case errors.As(err, &parseErr), errors.As(err, &unsupportedErr):
+			return newConfigParseError(c.opts.Fs, c.v.ConfigFileUsed(), err)
+		default:
 			// Config file was found but another error occurred
 			return fmt.Errorf("error reading config file: %w", err)
 		}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// testConfigYAML sets every test flag from its owning command section.
+const testConfigYAML = `
+cobravsviper:
+  rootpflag: value from config
+  grp:
+    grppflag: value from config
+    sub:
+      subflag: value from config
+`
+
+// newTestTree builds a three level command tree "cobravsviper grp sub" with a
+// persistent flag on the two first levels and a local flag on the leaf.
+func newTestTree() (root, grp, sub *cobra.Command) {
+	noop := func(cmd *cobra.Command, args []string) {}
+	root = &cobra.Command{Use: "cobravsviper", Run: noop}
+	grp = &cobra.Command{Use: "grp", Run: noop}
+	sub = &cobra.Command{Use: "sub", Run: noop}
+	root.AddCommand(grp)
+	grp.AddCommand(sub)
+
+	root.PersistentFlags().String("rootpflag", "value from default", "root persistent flag")
+	grp.PersistentFlags().String("grppflag", "value from default", "grp persistent flag")
+	sub.Flags().String("subflag", "value from default", "sub local flag")
+	return root, grp, sub
+}
+
+// TestInitViperSubCmdE_PrecedenceMatrix checks the priority chain
+// flags > env > dotenv > config > defaults of a flag owned by each level of the
+// command tree, when the leaf command runs. The commands are initialized in
+// both orders to make sure the result does not depend on the call order.
+func TestInitViperSubCmdE_PrecedenceMatrix(t *testing.T) {
+	levels := []struct {
+		owner string // command owning the flag
+		flag  string
+		key   string
+		env   string
+	}{
+		{"cobravsviper", "rootpflag", "cobravsviper.rootpflag", "COBRAVSVIPER_ROOTPFLAG"},
+		{"grp", "grppflag", "cobravsviper.grp.grppflag", "COBRAVSVIPER_GRP_GRPPFLAG"},
+		{"sub", "subflag", "cobravsviper.grp.sub.subflag", "COBRAVSVIPER_GRP_SUB_SUBFLAG"},
+	}
+	// each source also enables every source below it
+	sources := []string{"default", "config", "dotenv", "env", "flag"}
+	orders := map[string][]string{
+		"root to leaf": {"cobravsviper", "grp", "sub"},
+		"leaf to root": {"sub", "grp", "cobravsviper"},
+	}
+
+	for _, level := range levels {
+		for i, source := range sources {
+			for orderName, order := range orders {
+				t.Run(level.flag+"/"+source+"/"+orderName, func(t *testing.T) {
+					t.Parallel()
+					root, grp, sub := newTestTree()
+					cmds := map[string]*cobra.Command{"cobravsviper": root, "grp": grp, "sub": sub}
+
+					enabled := func(s string) bool {
+						for _, e := range sources[:i+1] {
+							if e == s {
+								return true
+							}
+						}
+						return false
+					}
+					env := map[string]string{}
+					if enabled("env") {
+						env[level.env] = "value from env"
+					}
+					if level.owner != "sub" {
+						// an env variable computed with the prefix of the leaf must be ignored
+						env["COBRAVSVIPER_GRP_SUB_"+strings.ToUpper(level.flag)] = "value from the wrong prefix"
+					}
+					c := newCLI(Options{Env: env, Fs: afero.NewMemMapFs()})
+					if enabled("config") {
+						c.v.SetConfigType("yaml")
+						if err := c.v.ReadConfig(strings.NewReader(testConfigYAML)); err != nil {
+							t.Fatalf("failed to read config: %v", err)
+						}
+					}
+					if enabled("dotenv") {
+						c.dotEnv[level.env] = "value from dotenv"
+					}
+
+					args := []string{}
+					if enabled("flag") {
+						args = append(args, "--"+level.flag, "value from flag")
+					}
+					if err := sub.ParseFlags(args); err != nil {
+						t.Fatalf("failed to parse flags: %v", err)
+					}
+
+					targets := map[string]map[string]any{}
+					for _, name := range order {
+						target := map[string]any{}
+						if err := c.InitViperSubCmdE(cmds[name], &target); err != nil {
+							t.Fatalf("InitViperSubCmdE(%s) failed: %v", name, err)
+						}
+						targets[name] = target
+					}
+
+					want := "value from " + source
+					if got := targets[level.owner][level.flag]; got != want {
+						t.Errorf("%s of %s = %q, want %q", level.flag, level.owner, got, want)
+					}
+					if got := c.v.GetString(level.key); got != want {
+						t.Errorf("viper key %s = %q, want %q", level.key, got, want)
+					}
+				})
+			}
+		}
+	}
+}
@@ -3,8 +3,19 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 */
 package main
 
-import "github.com/nicop311/cobravsviper/cmd"
+import (
+	"context"
+	"os"
+
+	"github.com/nicop311/cobravsviper/cmd"
+)
 
 func main() {
-	cmd.Execute()
+	// Cancel the context of the commands on SIGINT or SIGTERM, so that they
+	// can stop gracefully
+	ctx, stop := cmd.NotifyContext(context.Background())
+
+	result := cmd.Execute(ctx, os.Args[1:], cmd.IOStreams{In: os.Stdin, Out: os.Stdout, Err: os.Stderr}, nil)
+	stop()
+	os.Exit(result.ExitCode)
 }
@@ -0,0 +1,329 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed JSONPath template, in the syntax of kubectl: text with
+// expressions between braces, e.g. "{.name}: {.items[0].value}".
+//
+// The expressions are paths made of .field, ['field'], [index], [*] and .*
+// steps, string literals like "\n", and {range PATH} ... {end} blocks running
+// their body on each value of PATH. Paths start at the result, or at the
+// current value inside a range block; a leading $ or @ is ignored. Filters,
+// slices and recursive descent are not supported.
+type jsonPath struct {
+	nodes []jsonPathNode
+}
+
+// jsonPathNode is a textNode, a pathNode or a *rangeNode.
+type jsonPathNode any
+
+// textNode is text written as is.
+type textNode string
+
+// pathNode is a path writing the values it selects, separated by spaces.
+type pathNode []pathStep
+
+// rangeNode runs its body on each value selected by its path.
+type rangeNode struct {
+	path pathNode
+	body []jsonPathNode
+}
+
+// pathStep is a step of a path: a field of an object, an index of a list
+// (negative indexes count from the end), or all the values of an object or a
+// list.
+type pathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the JSONPath template s. A template without braces is
+// a single expression, so "jsonpath=.name" is "jsonpath={.name}".
+func parseJSONPath(s string) (*jsonPath, error) {
+	if !strings.Contains(s, "{") {
+		s = "{" + s + "}"
+	}
+	root := &rangeNode{}
+	stack := []*rangeNode{root}
+	for s != "" {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			start = len(s)
+		}
+		current := stack[len(stack)-1]
+		if start > 0 {
+			current.body = append(current.body, textNode(s[:start]))
+			s = s[start:]
+			continue
+		}
+		end := expressionEnd(s)
+		if end < 0 {
+			return nil, errors.New("unclosed brace")
+		}
+		expr := strings.TrimSpace(s[1:end])
+		s = s[end+1:]
+
+		switch {
+		case expr == "end":
+			if len(stack) == 1 {
+				return nil, errors.New("{end} without {range}")
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(expr, "range "):
+			path, err := parsePath(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, err
+			}
+			block := &rangeNode{path: path}
+			current.body = append(current.body, block)
+			stack = append(stack, block)
+		case strings.HasPrefix(expr, `"`), strings.HasPrefix(expr, "'"):
+			text, err := unquote(expr)
+			if err != nil {
+				return nil, err
+			}
+			current.body = append(current.body, textNode(text))
+		default:
+			path, err := parsePath(expr)
+			if err != nil {
+				return nil, err
+			}
+			current.body = append(current.body, path)
+		}
+	}
+	if len(stack) > 1 {
+		return nil, errors.New("{range} without {end}")
+	}
+	return &jsonPath{nodes: root.body}, nil
+}
+
+// unquote returns the text of the string literal s, between double or single
+// quotes, with the escape sequences of Go.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("invalid string literal %s", s)
+	}
+	quoted := s
+	if s[0] == '\'' {
+		quoted = `"` + strings.ReplaceAll(s[1:len(s)-1], `"`, `\"`) + `"`
+	}
+	text, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", fmt.Errorf("invalid string literal %s", s)
+	}
+	return text, nil
+}
+
+// expressionEnd returns the index of the brace closing the expression opened
+// at the start of s, skipping the quoted strings, or -1 if there is none.
+func expressionEnd(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == '\\':
+			i++
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote != 0:
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// parsePath parses the path expr, e.g. ".items[*].name".
+func parsePath(expr string) (pathNode, error) {
+	if expr == "" {
+		return nil, errors.New("empty expression")
+	}
+	s := strings.TrimLeft(expr, "$@")
+	path := pathNode{}
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			return nil, fmt.Errorf("recursive descent is not supported in %s", expr)
+		case s == ".":
+			s = ""
+		case strings.HasPrefix(s, ".*"):
+			path = append(path, pathStep{wildcard: true})
+			s = s[2:]
+		case s[0] == '.':
+			end := strings.IndexAny(s[1:], ".[")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			path = append(path, pathStep{field: s[1 : end+1]})
+			s = s[end+1:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in %s", expr)
+			}
+			step, err := parseBracket(s[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("%w in %s", err, expr)
+			}
+			path = append(path, step)
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q in %s", s, expr)
+		}
+	}
+	return path, nil
+}
+
+// parseBracket parses the step between brackets s: *, a quoted field or an
+// index.
+func parseBracket(s string) (pathStep, error) {
+	switch {
+	case s == "*":
+		return pathStep{wildcard: true}, nil
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		return pathStep{field: s[1 : len(s)-1]}, nil
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return pathStep{}, fmt.Errorf("unsupported subscript [%s]", s)
+	}
+	return pathStep{index: index, isIndex: true}, nil
+}
+
+// write writes the template evaluated on the generic value v to w.
+func (p *jsonPath) write(w io.Writer, v any) error {
+	var b strings.Builder
+	if err := writeNodes(&b, p.nodes, v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeNodes writes nodes evaluated on the generic value v to b.
+func writeNodes(b *strings.Builder, nodes []jsonPathNode, v any) error {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case textNode:
+			b.WriteString(string(node))
+		case pathNode:
+			values, err := node.eval(v)
+			if err != nil {
+				return err
+			}
+			for i, value := range values {
+				if i > 0 {
+					b.WriteByte(' ')
+				}
+				b.WriteString(cell(value))
+			}
+		case *rangeNode:
+			values, err := node.path.eval(v)
+			if err != nil {
+				return err
+			}
+			if len(values) == 1 {
+				if list, ok := values[0].([]any); ok {
+					values = list
+				}
+			}
+			for _, value := range values {
+				if err := writeNodes(b, node.body, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// eval returns the values selected by the path from the generic value v. It
+// returns an error if a field or an index does not exist.
+func (path pathNode) eval(v any) ([]any, error) {
+	values := []any{v}
+	for _, step := range path {
+		var next []any
+		for _, value := range values {
+			switch value := value.(type) {
+			case *object:
+				switch {
+				case step.wildcard:
+					for _, key := range value.keys {
+						next = append(next, value.values[key])
+					}
+				case step.isIndex:
+					return nil, fmt.Errorf("cannot index an object with [%d]", step.index)
+				default:
+					field, ok := value.values[step.field]
+					if !ok {
+						return nil, fmt.Errorf("field %q not found", step.field)
+					}
+					next = append(next, field)
+				}
+			case []any:
+				switch {
+				case step.wildcard:
+					next = append(next, value...)
+				case step.isIndex:
+					index := step.index
+					if index < 0 {
+						index += len(value)
+					}
+					if index < 0 || index >= len(value) {
+						return nil, fmt.Errorf("index [%d] out of range of a list of %d", step.index, len(value))
+					}
+					next = append(next, value[index])
+				default:
+					return nil, fmt.Errorf("cannot get field %q of a list", step.field)
+				}
+			default:
+				return nil, fmt.Errorf("cannot get %s of %s", step, kind(value))
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// String returns the step in the JSONPath syntax.
+func (step pathStep) String() string {
+	switch {
+	case step.wildcard:
+		return "[*]"
+	case step.isIndex:
+		return fmt.Sprintf("[%d]", step.index)
+	default:
+		return fmt.Sprintf("field %q", step.field)
+	}
+}
@@ -0,0 +1,528 @@
+// MIT License
+//
+// Copyright (c) 2025 Thales. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package output renders the results of the commands in the formats of the
+// -o/--output flag: JSON, YAML, TOML, a table, a Go text/template or a
+// JSONPath expression.
+//
+// The results are structs or maps marshaled to JSON first, so their field
+// names are the ones of their json tags in every format, except for the
+// templates executed on the result itself.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a format of the -o/--output flag.
+type Format struct {
+	// Name is the name of the format, e.g. json.
+	Name string
+	// Arg is the placeholder of the argument of the format, given after "=",
+	// e.g. TEMPLATE for template=TEMPLATE, or "" if it takes none.
+	Arg string
+	// Description is a short description of the format, shown by the shell
+	// completion.
+	Description string
+	// Print writes v in the format. It is nil for the Formats, and set for the
+	// formats of a kind of result added with Options.Formats.
+	Print func(w io.Writer, v any) error
+}
+
+// Formats are the formats of the -o/--output flag, in the order they are
+// documented and completed.
+var Formats = []Format{
+	{Name: "json", Description: "JSON"},
+	{Name: "yaml", Description: "YAML"},
+	{Name: "toml", Description: "TOML"},
+	{Name: "table", Description: "table with a header"},
+	{Name: "template", Arg: "TEMPLATE", Description: "Go text/template, e.g. template={{.Name}}"},
+	{Name: "template-file", Arg: "FILE", Description: "Go text/template read from a file"},
+	{Name: "jsonpath", Arg: "EXPR", Description: "JSONPath subset of kubectl, without filters, slices nor recursive descent, e.g. jsonpath={.name}"},
+}
+
+// JSONPathSyntax describes the subset of the JSONPath syntax of kubectl
+// supported by the jsonpath format (see jsonPath), for the help of the
+// commands.
+const JSONPathSyntax = `The jsonpath format supports the .field, ['field'], [index] (negative from the end), [*] and .* steps, the "text" literals and {range PATH}...{end}; filters, slices and recursive descent are not supported.`
+
+// Names returns the names of formats, followed by "=" for the ones taking an
+// argument.
+func Names(formats []Format) []string {
+	names := make([]string, 0, len(formats))
+	for _, f := range formats {
+		if f.Arg != "" {
+			names = append(names, f.Name+"=")
+		} else {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// ParseFormat splits format, e.g. "template={{.Name}}", into one of formats
+// and its argument. It returns an error if the format is unknown, or if its
+// argument is missing or unexpected.
+func ParseFormat(formats []Format, format string) (f Format, arg string, err error) {
+	name, arg, hasArg := strings.Cut(format, "=")
+	for _, f := range formats {
+		if f.Name != name {
+			continue
+		}
+		switch {
+		case f.Arg != "" && arg == "":
+			return Format{}, "", fmt.Errorf("output format %s expects an argument: %s=%s", name, name, f.Arg)
+		case f.Arg == "" && hasArg:
+			return Format{}, "", fmt.Errorf("output format %s expects no argument", name)
+		}
+		return f, arg, nil
+	}
+	return Format{}, "", fmt.Errorf("unknown output format %q, expected one of %s", format, strings.Join(Names(formats), ", "))
+}
+
+// Options are the options of a Printer.
+type Options struct {
+	// Compact writes the JSON on a single line instead of indenting it.
+	Compact bool
+	// Color writes the header of the tables in bold.
+	Color bool
+	// Formats are the supported formats, Formats by default. A kind of result
+	// adds its own formats by appending them to Formats.
+	Formats []Format
+	// ReadFile reads the file of the template-file format, os.ReadFile by
+	// default.
+	ReadFile func(name string) ([]byte, error)
+}
+
+// Tabular is implemented by the results choosing their own columns in the
+// table format. The other results are written as a table with a column per
+// field for a list of objects, or as KEY and VALUE columns otherwise.
+type Tabular interface {
+	// Table returns the header and the rows of the table.
+	Table() (header []string, rows [][]string)
+}
+
+// Printer writes results in an output format.
+type Printer struct {
+	format Format
+	opts   Options
+	tmpl   *template.Template
+	path   *jsonPath
+}
+
+// NewPrinter returns the Printer of format, one of the formats of opts with
+// its argument if any. It returns an error if the format is invalid, including
+// when its template or JSONPath expression cannot be parsed, or its template
+// file cannot be read.
+func NewPrinter(format string, opts Options) (*Printer, error) {
+	if opts.Formats == nil {
+		opts.Formats = Formats
+	}
+	if opts.ReadFile == nil {
+		opts.ReadFile = os.ReadFile
+	}
+	f, arg, err := ParseFormat(opts.Formats, format)
+	if err != nil {
+		return nil, err
+	}
+	p := &Printer{format: f, opts: opts}
+	switch f.Name {
+	case "template-file":
+		content, err := opts.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read template file: %w", err)
+		}
+		p.tmpl, err = template.New(filepath.Base(arg)).Funcs(templateFuncs).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid template file: %w", err)
+		}
+	case "template":
+		p.tmpl, err = template.New("output").Funcs(templateFuncs).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+	case "jsonpath":
+		p.path, err = parseJSONPath(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath expression %q: %w", arg, err)
+		}
+	}
+	return p, nil
+}
+
+// Render writes v to w in format. See NewPrinter and Printer.Print.
+func Render(w io.Writer, format string, v any, opts Options) error {
+	p, err := NewPrinter(format, opts)
+	if err != nil {
+		return err
+	}
+	return p.Print(w, v)
+}
+
+// templateFuncs are the functions of the templates, in addition to the
+// builtin functions of text/template.
+var templateFuncs = template.FuncMap{
+	// json returns v marshaled to JSON on a single line
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Print writes v to w. The JSON, YAML, TOML and table formats end with a
+// newline; the templates and JSONPath expressions write exactly what they
+// evaluate to, so they can be used in shell substitutions.
+func (p *Printer) Print(w io.Writer, v any) error {
+	if p.format.Print != nil {
+		return p.format.Print(w, v)
+	}
+	switch p.format.Name {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		if !p.opts.Compact {
+			enc.SetIndent("", "  ")
+		}
+		return enc.Encode(v)
+	case "template", "template-file":
+		return p.tmpl.Execute(w, v)
+	}
+
+	if tabular, ok := v.(Tabular); ok && p.format.Name == "table" {
+		header, rows := tabular.Table()
+		return writeTable(w, header, rows, p.opts.Color)
+	}
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	switch p.format.Name {
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(yamlNode(generic)); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		object, ok := generic.(*object)
+		if !ok {
+			return fmt.Errorf("output format toml expects an object, got %s", kind(generic))
+		}
+		enc := toml.NewEncoder(w)
+		enc.SetIndentTables(true)
+		return enc.Encode(tomlValue(object))
+	case "table":
+		header, rows := genericTable(generic)
+		return writeTable(w, header, rows, p.opts.Color)
+	default: // jsonpath
+		return p.path.write(w, generic)
+	}
+}
+
+// object is a JSON object keeping the order of its keys.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+// toGeneric marshals v to JSON and decodes it to *object, []any, string,
+// json.Number, bool or nil values, keeping the order of the keys.
+func toGeneric(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return decodeGeneric(dec)
+}
+
+// decodeGeneric decodes the next JSON value of dec (see toGeneric).
+func decodeGeneric(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &object{values: map[string]any{}}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeGeneric(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, key.(string))
+			obj.values[key.(string)] = value
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []any{}
+		for dec.More() {
+			value, err := decodeGeneric(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	default:
+		return tok, nil
+	}
+}
+
+// kind returns the JSON kind of the generic value v, for the error messages.
+func kind(v any) string {
+	switch v.(type) {
+	case *object:
+		return "an object"
+	case []any:
+		return "a list"
+	case nil:
+		return "null"
+	default:
+		return "a scalar"
+	}
+}
+
+// yamlNode returns the YAML node of the generic value v, in block style.
+func yamlNode(v any) *yaml.Node {
+	switch v := v.(type) {
+	case *object:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range v.keys {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, yamlNode(v.values[key]))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v)}
+	}
+}
+
+// tomlValue returns the generic value v with maps instead of objects and Go
+// numbers instead of json.Number. The null values are dropped, TOML has none.
+func tomlValue(v any) any {
+	switch v := v.(type) {
+	case *object:
+		m := make(map[string]any, len(v.keys))
+		for _, key := range v.keys {
+			if v.values[key] != nil {
+				m[key] = tomlValue(v.values[key])
+			}
+		}
+		return m
+	case []any:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				list = append(list, tomlValue(item))
+			}
+		}
+		return list
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+// genericTable returns the table of the generic value v: a column per key for
+// a list of objects, KEY and VALUE columns with the dotted paths of the
+// scalars for an object, a single VALUE column otherwise.
+func genericTable(v any) (header []string, rows [][]string) {
+	switch v := v.(type) {
+	case []any:
+		if len(v) == 0 {
+			return nil, nil
+		}
+		var columns []string
+		seen := map[string]bool{}
+		for _, item := range v {
+			obj, ok := item.(*object)
+			if !ok {
+				return genericValueTable(v)
+			}
+			for _, key := range obj.keys {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+		}
+		for _, item := range v {
+			obj := item.(*object)
+			row := make([]string, len(columns))
+			for i, key := range columns {
+				row[i] = cell(obj.values[key])
+			}
+			rows = append(rows, row)
+		}
+		return columns, rows
+	case *object:
+		var walk func(prefix string, obj *object)
+		walk = func(prefix string, obj *object) {
+			for _, key := range obj.keys {
+				if nested, ok := obj.values[key].(*object); ok {
+					walk(prefix+key+".", nested)
+					continue
+				}
+				rows = append(rows, []string{prefix + key, cell(obj.values[key])})
+			}
+		}
+		walk("", v)
+		return []string{"key", "value"}, rows
+	default:
+		return genericValueTable(v)
+	}
+}
+
+// genericValueTable returns the table of a scalar or a list of scalars.
+func genericValueTable(v any) (header []string, rows [][]string) {
+	list, ok := v.([]any)
+	if !ok {
+		list = []any{v}
+	}
+	for _, item := range list {
+		rows = append(rows, []string{cell(item)})
+	}
+	return []string{"value"}, rows
+}
+
+// cell returns the text of the generic value v in a table: the scalars as is,
+// the lists of scalars joined with commas, the other values in JSON.
+func cell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case *object:
+		return compactJSON(v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case *object, []any:
+				return compactJSON(v)
+			}
+			items[i] = cell(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// compactJSON returns the generic value v in JSON on a single line.
+func compactJSON(v any) string {
+	var b strings.Builder
+	var encode func(v any)
+	encode = func(v any) {
+		switch v := v.(type) {
+		case *object:
+			b.WriteByte('{')
+			for i, key := range v.keys {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				encode(key)
+				b.WriteByte(':')
+				encode(v.values[key])
+			}
+			b.WriteByte('}')
+		case []any:
+			b.WriteByte('[')
+			for i, item := range v {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				encode(item)
+			}
+			b.WriteByte(']')
+		default:
+			data, _ := json.Marshal(v)
+			b.Write(data)
+		}
+	}
+	encode(v)
+	return b.String()
+}
+
+// writeTable writes the header, in upper case and in bold if color is set,
+// and the rows of a table to w, with columns aligned by spaces.
+func writeTable(w io.Writer, header []string, rows [][]string, color bool) error {
+	var b bytes.Buffer
+	tw := tabwriter.NewWriter(&b, 0, 0, 3, ' ', 0)
+	if len(header) > 0 {
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	table := b.String()
+	if color && len(header) > 0 {
+		// Color the header after aligning the columns, the escape sequences
+		// would count in the widths otherwise
+		first, rest, _ := strings.Cut(table, "\n")
+		table = "\x1b[1m" + strings.TrimRight(first, " ") + "\x1b[0m\n" + rest
+	}
+	_, err := io.WriteString(w, table)
+	return err
+}
@@ -0,0 +1,209 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// result is a result with nested objects and lists, rendered by the tests.
+type result struct {
+	Name  string            `json:"name"`
+	Count int               `json:"count"`
+	Ok    bool              `json:"ok"`
+	Tags  []string          `json:"tags"`
+	Items []item            `json:"items"`
+	Meta  map[string]string `json:"meta,omitempty"`
+}
+
+type item struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+var testResult = result{
+	Name:  "true",
+	Count: 2,
+	Ok:    true,
+	Tags:  []string{"a", "b"},
+	Items: []item{{"k1", "v1"}, {"k2", "v2"}},
+}
+
+// TestRender checks the output of each format, keeping the order of the
+// fields of the result.
+func TestRender(t *testing.T) {
+	cases := []struct {
+		format string
+		opts   Options
+		want   string
+	}{
+		{"json", Options{Compact: true}, `{"name":"true","count":2,"ok":true,"tags":["a","b"],"items":[{"key":"k1","value":"v1"},{"key":"k2","value":"v2"}]}` + "\n"},
+		{"yaml", Options{}, `name: "true"
+count: 2
+ok: true
+tags:
+  - a
+  - b
+items:
+  - key: k1
+    value: v1
+  - key: k2
+    value: v2
+`},
+		{"toml", Options{}, `count = 2
+name = 'true'
+ok = true
+tags = ['a', 'b']
+
+[[items]]
+  key = 'k1'
+  value = 'v1'
+
+[[items]]
+  key = 'k2'
+  value = 'v2'
+`},
+		{"table", Options{}, `KEY     VALUE
+name    true
+count   2
+ok      true
+tags    a,b
+items   [{"key":"k1","value":"v1"},{"key":"k2","value":"v2"}]
+`},
+		{"table", Options{Color: true}, "\x1b[1mKEY     VALUE\x1b[0m\nname    true\n"},
+		{"template={{.Name}} has {{len .Items}} items", Options{}, "true has 2 items"},
+		{"template={{json .Tags}}", Options{}, `["a","b"]`},
+		{"jsonpath={.items[*].key}", Options{}, "k1 k2"},
+		{"jsonpath=.items[-1].value", Options{}, "v2"},
+		{"jsonpath={.name}:{.tags}", Options{}, "true:a,b"},
+		{`jsonpath={range .items[*]}{.key}={.value}{"\n"}{end}`, Options{}, "k1=v1\nk2=v2\n"},
+		{"jsonpath={.items[0]}", Options{}, `{"key":"k1","value":"v1"}`},
+	}
+
+	for _, tc := range cases {
+		var b bytes.Buffer
+		if err := Render(&b, tc.format, testResult, tc.opts); err != nil {
+			t.Errorf("Render(%q) returned error: %v", tc.format, err)
+			continue
+		}
+		// The colored table is only checked up to its first row
+		if got := b.String(); got != tc.want && !(tc.opts.Color && strings.HasPrefix(got, tc.want)) {
+			t.Errorf("Render(%q) =\n%s\nexpected:\n%s", tc.format, got, tc.want)
+		}
+	}
+}
+
+// TestRender_Table checks the table of a list of objects has a column per
+// field, and that the Tabular results choose their columns.
+func TestRender_Table(t *testing.T) {
+	var b bytes.Buffer
+	if err := Render(&b, "table", testResult.Items, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "KEY   VALUE\nk1    v1\nk2    v2\n"; b.String() != want {
+		t.Errorf("got:\n%s\nexpected:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := Render(&b, "table", tabular{}, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "A   B\n1   2\n"; b.String() != want {
+		t.Errorf("got:\n%s\nexpected:\n%s", b.String(), want)
+	}
+}
+
+type tabular struct{}
+
+func (tabular) Table() ([]string, [][]string) {
+	return []string{"a", "b"}, [][]string{{"1", "2"}}
+}
+
+// TestRender_Invalid checks the invalid formats are reported by NewPrinter,
+// and the expressions that cannot be evaluated by Print.
+func TestRender_Invalid(t *testing.T) {
+	cases := []struct {
+		format    string
+		parseErr  string
+		renderErr string
+	}{
+		{"xml", `unknown output format "xml"`, ""},
+		{"json=x", "expects no argument", ""},
+		{"template", "expects an argument: template=TEMPLATE", ""},
+		{"template={{.Name", "invalid template", ""},
+		{"jsonpath={.name", "unclosed brace", ""},
+		{"jsonpath={range .items}", "{range} without {end}", ""},
+		{"jsonpath={..name}", "recursive descent is not supported", ""},
+		{"jsonpath={.items[1:2]}", "unsupported subscript", ""},
+		{"jsonpath={.missing}", "", `field "missing" not found`},
+		{"jsonpath={.items[5]}", "", "index [5] out of range"},
+		{"jsonpath={.name.first}", "", "of a scalar"},
+		{"template={{.Missing}}", "", "Missing"},
+	}
+
+	for _, tc := range cases {
+		p, err := NewPrinter(tc.format, Options{})
+		if tc.parseErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.parseErr) {
+				t.Errorf("NewPrinter(%q) returned error %v, expected %q", tc.format, err, tc.parseErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewPrinter(%q) returned error: %v", tc.format, err)
+			continue
+		}
+		if err := p.Print(&bytes.Buffer{}, testResult); err == nil || !strings.Contains(err.Error(), tc.renderErr) {
+			t.Errorf("Print(%q) returned error %v, expected %q", tc.format, err, tc.renderErr)
+		}
+	}
+}
+
+// TestRender_Options checks the templates read from a file, and the formats
+// added with Options.Formats.
+func TestRender_Options(t *testing.T) {
+	readFile := func(name string) ([]byte, error) {
+		if name != "result.tmpl" {
+			return nil, os.ErrNotExist
+		}
+		return []byte("{{.Name}}: {{.Count}}\n"), nil
+	}
+	upper := Format{Name: "upper", Description: "name in upper case", Print: func(w io.Writer, v any) error {
+		_, err := io.WriteString(w, strings.ToUpper(v.(result).Name))
+		return err
+	}}
+	formats := append(slices.Clone(Formats), upper)
+
+	cases := []struct {
+		format string
+		opts   Options
+		want   string
+		err    string
+	}{
+		{"template-file=result.tmpl", Options{ReadFile: readFile}, "true: 2\n", ""},
+		{"template-file=missing.tmpl", Options{ReadFile: readFile}, "", "cannot read template file"},
+		{"upper", Options{Formats: formats}, "TRUE", ""},
+		{"json", Options{Formats: formats, Compact: true}, `{"name":"true"`, ""},
+		{"upper", Options{}, "", `unknown output format "upper"`},
+	}
+
+	for _, tc := range cases {
+		var b bytes.Buffer
+		err := Render(&b, tc.format, testResult, tc.opts)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Render(%q) returned error %v, expected %q", tc.format, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || !strings.HasPrefix(b.String(), tc.want) {
+			t.Errorf("Render(%q) = %q, %v, expected %q", tc.format, b.String(), err, tc.want)
+		}
+	}
+	if got := Names(formats); got[len(got)-1] != "upper" || !slices.Contains(got, "template-file=") {
+		t.Errorf("Names() = %v", got)
+	}
+}
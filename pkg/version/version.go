@@ -21,8 +21,16 @@ package version
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
 
 	go_version "github.com/hashicorp/go-version"
+	"github.com/nicop311/cobravsviper/pkg/output"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
@@ -39,9 +47,11 @@ var (
 	BuildDate          string
 )
 
-// VersionDetails represents the JSON & YAML output structure.
+// VersionDetails represents the JSON & YAML output structure. VersionData is
+// embedded so the templates of --output reach its fields directly, e.g.
+// {{.Major}}, while the JSON and YAML nest them under "cobravsviper".
 type VersionDetails struct {
-	VersionData VersionData `json:"cobravsviper" yaml:"cobravsviper"`
+	VersionData `json:"cobravsviper" yaml:"cobravsviper"`
 }
 
 // VersionData holds structured versioning details.
@@ -49,7 +59,11 @@ type VersionData struct {
 	Major              uint64 `json:"major" yaml:"major"`
 	Minor              uint64 `json:"minor" yaml:"minor"`
 	Patch              uint64 `json:"patch" yaml:"patch"`
-	Version            string `json:"version" yaml:"version"` // raw git describe
+	Prerelease         string `json:"prerelease" yaml:"prerelease"` // e.g. rc.1 for v1.2.3-rc.1
+	Metadata           string `json:"metadata" yaml:"metadata"`     // e.g. build.7 for v1.2.3+build.7
+	Version            string `json:"version" yaml:"version"`       // raw git describe
+	ClosestTag         string `json:"closestTag" yaml:"closestTag"` // tag git describe is based on, empty for a snapshot
+	CommitsSinceTag    uint64 `json:"commitsSinceTag" yaml:"commitsSinceTag"`
 	IsGitDirty         bool   `json:"isGitDirty" yaml:"isGitDirty"`
 	GitCommitIdLong    string `json:"gitCommitIdLong" yaml:"gitCommitIdLong"`
 	GitCommitIdShort   string `json:"gitCommitIdShort" yaml:"gitCommitIdShort"`
@@ -75,13 +89,18 @@ func IsDirty(isDirtyStr string) (bool, error) {
 	case "false":
 		return false, nil
 	default:
-		logrus.WithField("GitDirtyStr", isDirtyStr).Warn("Unexpected Git dirty string, assuming clean")
-		return false, fmt.Errorf("invalid dirty information: %s", GitDirtyStr)
+		return false, fmt.Errorf("invalid dirty information: %q", isDirtyStr)
 	}
 }
 
 // unset (zero v0.0.0).
 func NewVersionData() (VersionData, error) {
+	return NewVersionDataWithLogger(logrus.StandardLogger())
+}
+
+// NewVersionDataWithLogger is NewVersionData logging the issues to log, e.g.
+// the logger of a command.
+func NewVersionDataWithLogger(log logrus.FieldLogger) (VersionData, error) {
 	// this is a minimal content of the VersionData information
 	versionData := VersionData{
 		Version:            RawGitDescribe,
@@ -97,14 +116,20 @@ func NewVersionData() (VersionData, error) {
 	isDirty, err := IsDirty(GitDirtyStr)
 	if err != nil {
 		// only do a warning, do not return an error
-		logrus.WithError(err).Warning("Failed to parse Git dirty status")
+		log.WithError(err).Warning("Failed to parse Git dirty status, assuming clean")
 	}
 	versionData.IsGitDirty = isDirty
 
-	// Check if RawGitDescribe is a valid semantic version or a commit hash
-	version, err := go_version.NewSemver(RawGitDescribe)
+	// Check if the tag of RawGitDescribe is a valid semantic version or if
+	// RawGitDescribe is a commit hash
+	tag, commitsSinceTag := parseGitDescribe(RawGitDescribe)
+	if !semverTag.MatchString(tag) {
+		log.WithField("raw_git_describe", RawGitDescribe).Debug("No semantic version tag, falling back to snapshot version")
+		return versionData, nil
+	}
+	version, err := go_version.NewSemver(tag)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
+		log.WithFields(logrus.Fields{
 			"raw_git_describe": RawGitDescribe,
 			"error":            err,
 		}).Debug("Invalid semantic versioning, falling back to snapshot version")
@@ -124,22 +149,165 @@ func NewVersionData() (VersionData, error) {
 	versionData.Major = uint64(versionSegments[0])
 	versionData.Minor = uint64(versionSegments[1])
 	versionData.Patch = uint64(versionSegments[2])
+	versionData.Prerelease = version.Prerelease()
+	versionData.Metadata = version.Metadata()
+	versionData.ClosestTag = tag
+	versionData.CommitsSinceTag = commitsSinceTag
 
 	return versionData, nil
 }
 
+// semverTag matches the tags with the shape of a semantic version,
+// v?MAJOR.MINOR.PATCH with an optional prerelease and build metadata, unlike
+// a commit hash made of digits only, e.g. 1234567.
+var semverTag = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// gitDescribeSuffix matches the suffix git describe adds to the closest tag
+// when the commit is not tagged: the number of commits since the tag and the
+// abbreviated commit hash, e.g. -5-gabc123.
+var gitDescribeSuffix = regexp.MustCompile(`-(\d+)-g[0-9a-f]+$`)
+
+// parseGitDescribe splits the output of git describe --tags --always [--dirty]
+// into the closest tag and the number of commits since it, e.g. v1.2.3-rc.1
+// and 5 for v1.2.3-rc.1-5-gabc123. The -dirty suffix is ignored. Without tag,
+// git describe returns the commit hash, returned as the tag.
+func parseGitDescribe(describe string) (tag string, commitsSinceTag uint64) {
+	tag = strings.TrimSuffix(describe, "-dirty")
+	match := gitDescribeSuffix.FindStringSubmatchIndex(tag)
+	if match == nil {
+		return tag, 0
+	}
+	commitsSinceTag, err := strconv.ParseUint(tag[match[2]:match[3]], 10, 64)
+	if err != nil {
+		return tag, 0
+	}
+	return tag[:match[0]], commitsSinceTag
+}
+
 // NewVersionDetails creates a new VersionDetails object using NewVersionData.
 func NewVersionDetails() (VersionDetails, error) {
-	versionData, err := NewVersionData()
+	return NewVersionDetailsWithLogger(logrus.StandardLogger())
+}
+
+// NewVersionDetailsWithLogger is NewVersionDetails logging the issues to log.
+func NewVersionDetailsWithLogger(log logrus.FieldLogger) (VersionDetails, error) {
+	versionData, err := NewVersionDataWithLogger(log)
 	if err != nil {
 		return VersionDetails{}, err
 	}
 	return VersionDetails{VersionData: versionData}, nil
 }
 
+// CheckError is returned by Check when the version does not satisfy the
+// constraints.
+type CheckError struct {
+	// Version is the raw git describe of the version, e.g. v1.2.3.
+	Version string
+	// Constraints are the constraints checked, e.g. ">= 1.4, < 2.0".
+	Constraints string
+	// Snapshot is set when the version is not a semantic version.
+	Snapshot bool
+	// Dirty is set when the version is built from a dirty git tree.
+	Dirty bool
+}
+
+func (e *CheckError) Error() string {
+	switch {
+	case e.Snapshot:
+		return fmt.Sprintf("version %q is a snapshot build, not checked against %s", e.Version, e.Constraints)
+	case e.Dirty:
+		return fmt.Sprintf("version %s is a dirty build, not checked against %s", e.Version, e.Constraints)
+	default:
+		return fmt.Sprintf("version %s does not satisfy %s", e.Version, e.Constraints)
+	}
+}
+
+// Check checks the semantic version of versionData, its closest tag with its
+// prerelease, against constraints in the syntax of hashicorp/go-version, e.g.
+// ">= 1.4, < 2.0". It returns a *CheckError if the version does not satisfy
+// them, and another error if they cannot be parsed.
+//
+// A prerelease comes before its release, as in semantic versioning: v1.4.0-rc.1
+// does not satisfy ">= 1.4" and v2.0.0-rc.1 satisfies "< 2.0".
+//
+// The snapshot builds, without a semantic version tag (see
+// VersionData.ClosestTag), fail the check unless allowSnapshot is set: they
+// pass it then, without being checked. The builds from a dirty git tree fail
+// the check unless allowDirty is set: they are checked like the others then.
+func Check(versionData VersionData, constraints string, allowSnapshot, allowDirty bool) error {
+	parsed, err := go_version.NewConstraint(constraints)
+	if err != nil {
+		return fmt.Errorf("invalid version constraints %q: %w", constraints, err)
+	}
+
+	checkErr := &CheckError{Version: versionData.Version, Constraints: constraints}
+	if versionData.ClosestTag == "" {
+		if allowSnapshot {
+			return nil
+		}
+		checkErr.Snapshot = true
+		return checkErr
+	}
+	if versionData.IsGitDirty && !allowDirty {
+		checkErr.Dirty = true
+		return checkErr
+	}
+
+	version, err := go_version.NewSemver(versionData.ClosestTag)
+	if err != nil {
+		return err
+	}
+	for _, constraint := range parsed {
+		if !checkConstraint(constraint, version) {
+			return checkErr
+		}
+	}
+	return nil
+}
+
+// constraintOperator matches a single hashicorp/go-version constraint: its
+// operator, "=" when missing, and its version.
+var constraintOperator = regexp.MustCompile(`^\s*(=|!=|>=|<=|>|<|~>)?\s*(\S+)\s*$`)
+
+// checkConstraint checks version against a single constraint. go-version only
+// matches a prerelease against a constraint on a prerelease of the same
+// version: the other constraints are checked by semantic versioning
+// precedence, the prerelease coming before its release.
+func checkConstraint(constraint *go_version.Constraint, version *go_version.Version) bool {
+	if version.Prerelease() == "" || constraint.Prerelease() {
+		return constraint.Check(version)
+	}
+	match := constraintOperator.FindStringSubmatch(constraint.String())
+	if match == nil {
+		return false
+	}
+	bound, err := go_version.NewVersion(match[2])
+	if err != nil {
+		return false
+	}
+	cmp := version.Compare(bound)
+	switch match[1] {
+	case "", "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	default:
+		// ~>: at least the bound, and its release within the range
+		return cmp >= 0 && constraint.Check(version.Core())
+	}
+}
+
 // returnJsonVersion returns the version as a JSON object.
-func returnJsonVersion(prettyPrint bool) ([]byte, error) {
-	versionDetails, err := NewVersionDetails()
+func returnJsonVersion(log logrus.FieldLogger, prettyPrint bool) ([]byte, error) {
+	versionDetails, err := NewVersionDetailsWithLogger(log)
 	if err != nil {
 		return nil, err
 	}
@@ -151,15 +319,15 @@ func returnJsonVersion(prettyPrint bool) ([]byte, error) {
 }
 
 // returnYamlVersion returns the version as a YAML object.
-func returnYamlVersion() ([]byte, error) {
-	versionDetails, err := NewVersionDetails()
+func returnYamlVersion(log logrus.FieldLogger) ([]byte, error) {
+	versionDetails, err := NewVersionDetailsWithLogger(log)
 	if err != nil {
 		return nil, err
 	}
 
 	yamlData, err := yaml.Marshal(versionDetails)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to marshal YAML")
+		log.WithError(err).Error("Failed to marshal YAML")
 		return nil, err
 	}
 	return yamlData, nil
@@ -167,48 +335,139 @@ func returnYamlVersion() ([]byte, error) {
 
 // LogrusOutputVersion logs the version details at server startup. For server logging.
 func LogrusOutputVersion() {
-	versionData, err := NewVersionData()
+	LogrusOutputVersionWithLogger(logrus.StandardLogger())
+}
+
+// LogrusOutputVersionWithLogger is LogrusOutputVersion logging to log.
+func LogrusOutputVersionWithLogger(log logrus.FieldLogger) {
+	versionData, err := NewVersionDataWithLogger(log)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to fetch version data")
+		log.WithError(err).Error("Failed to fetch version data")
 		return
 	}
 
-	logrus.Infof("cobravsviper version: %s", versionData.Version)
-	logrus.WithFields(logrus.Fields{
+	log.Infof("cobravsviper version: %s", versionData.Version)
+	log.WithFields(logrus.Fields{
 		"build-date":       versionData.BuildDate,
 		"build-platform":   versionData.BuildPlatform,
 		"commit":           versionData.GitCommitIdLong,
 		"go-version":       versionData.GoVersion,
 		"raw-git-describe": versionData.Version,
+		"closest-tag":      versionData.ClosestTag,
+		"commits-since":    versionData.CommitsSinceTag,
 		"is-git-dirty":     versionData.IsGitDirty,
 		"short-commit":     versionData.GitCommitIdShort,
 	}).Debug("cobravsviper version details")
 }
 
-// VersionOutputToString returns the version as a formatted string.
+// envPrefix is the prefix of the variables of the env output format.
+const envPrefix = "COBRAVSVIPER_"
+
+// Formats are the output formats of the version: the output formats of the
+// commands, env and short.
+var Formats = append(slices.Clone(output.Formats),
+	output.Format{Name: "env", Description: "KEY=VALUE lines, e.g. " + envPrefix + "VERSION=v1.2.3", Print: writeEnvVersionE},
+	output.Format{Name: "short", Description: "version only, e.g. v1.2.3", Print: writeShortVersionE},
+)
+
+// writeEnvVersionE writes the fields of the VersionDetails v to w as KEY=VALUE
+// lines that can be sourced by a shell, e.g. COBRAVSVIPER_GIT_COMMIT_ID_SHORT=abc123.
+func writeEnvVersionE(w io.Writer, v any) error {
+	details, ok := v.(VersionDetails)
+	if !ok {
+		return fmt.Errorf("env output expects version details, got %T", v)
+	}
+	value := reflect.ValueOf(details.VersionData)
+	for i := range value.NumField() {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if _, err := fmt.Fprintf(w, "%s%s=%s\n", envPrefix, envName(name), shellQuote(fmt.Sprint(value.Field(i).Interface()))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeShortVersionE writes the version of the VersionDetails v to w, e.g.
+// v1.2.3, or the raw git describe of a snapshot.
+func writeShortVersionE(w io.Writer, v any) error {
+	details, ok := v.(VersionDetails)
+	if !ok {
+		return fmt.Errorf("short output expects version details, got %T", v)
+	}
+	_, err := fmt.Fprintln(w, details.Version)
+	return err
+}
+
+// envName returns the camel case JSON name in upper snake case, e.g.
+// GIT_COMMIT_ID_SHORT for gitCommitIdShort.
+func envName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// shellQuote returns s quoted with single quotes if a shell would not read it
+// as a single word as is.
+func shellQuote(s string) string {
+	if strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_./:+@%-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// VersionOutputToString returns the version as a formatted string in
+// outputFormat, one of the Formats, e.g. "template={{.Major}}.{{.Minor}}" or
+// "jsonpath={.cobravsviper.gitCommitIdShort}". The templates are executed on
+// the VersionDetails. An unknown format returns the one liner
+// "cobravsviper: <version>".
 func VersionOutputToString(outputFormat string, prettyPrint bool) string {
+	return VersionOutputToStringWithLogger(logrus.StandardLogger(), outputFormat, prettyPrint)
+}
+
+// VersionOutputToStringWithLogger is VersionOutputToString logging the issues
+// to log.
+func VersionOutputToStringWithLogger(log logrus.FieldLogger, outputFormat string, prettyPrint bool) string {
 	switch outputFormat {
 	case "json":
-		data, err := returnJsonVersion(prettyPrint)
+		data, err := returnJsonVersion(log, prettyPrint)
 		if err != nil {
-			logrus.WithError(err).Error("Failed to generate JSON version output")
+			log.WithError(err).Error("Failed to generate JSON version output")
 			return "Error generating JSON output"
 		}
 		return string(data)
 	case "yaml":
-		data, err := returnYamlVersion()
+		data, err := returnYamlVersion(log)
 		if err != nil {
-			logrus.WithError(err).Error("Failed to generate YAML version output")
+			log.WithError(err).Error("Failed to generate YAML version output")
 			return "Error generating YAML output"
 		}
 		return string(data)
-	default:
+	}
+
+	name, _, _ := strings.Cut(outputFormat, "=")
+	if !slices.ContainsFunc(Formats, func(f output.Format) bool { return f.Name == name }) {
 		version, err := go_version.NewSemver(RawGitDescribe)
 		if err != nil {
-			logrus.WithError(err).Debug("Invalid semantic versioning, falling back to snapshot version")
+			log.WithError(err).Debug("Invalid semantic versioning, falling back to snapshot version")
 			return fmt.Sprintf("cobravsviper: (snapshot) %s", RawGitDescribe)
 		}
 
 		return fmt.Sprintf("cobravsviper: %s", version.String())
 	}
+
+	versionDetails, err := NewVersionDetailsWithLogger(log)
+	if err == nil {
+		var b strings.Builder
+		err = output.Render(&b, outputFormat, versionDetails, output.Options{Compact: !prettyPrint, Formats: Formats})
+		if err == nil {
+			return strings.TrimSuffix(b.String(), "\n")
+		}
+	}
+	log.WithError(err).Errorf("Failed to generate %s version output", name)
+	return fmt.Sprintf("Error generating %s output: %v", name, err)
 }
@@ -2,10 +2,20 @@ package version
 
 import (
 	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
 )
 
+// discardLogger is the logger of the tests that do not check the logs.
+var discardLogger, _ = logrustest.NewNullLogger()
+
 // Reset global vars before each test if needed
 func resetGlobals() {
 	RawGitDescribe = ""
@@ -41,14 +51,16 @@ func TestIsDirty(t *testing.T) {
 	}
 }
 
-// TestNewVersionData_Defaults tests the NewVersionData function with all global
+// TestNewVersionData_Defaults tests the NewVersionDataWithLogger function with all global
 // variables set to zero values. The test ensures that the function does not
-// return an error and that the IsGitDirty flag is set to false when the dirty
-// string is empty or invalid.
+// return an error, that the IsGitDirty flag is set to false when the dirty
+// string is empty or invalid and that the warning is logged to the given
+// logger.
 func TestNewVersionData_Defaults(t *testing.T) {
 	resetGlobals()
 
-	data, err := NewVersionData()
+	logger, hook := logrustest.NewNullLogger()
+	data, err := NewVersionDataWithLogger(logger.WithField("cobra-cmd", "version"))
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -56,6 +68,10 @@ func TestNewVersionData_Defaults(t *testing.T) {
 	if data.IsGitDirty != false {
 		t.Errorf("Expected IsGitDirty to be false when dirty string is empty or invalid")
 	}
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.WarnLevel || entry.Data["cobra-cmd"] != "version" {
+		t.Errorf("Expected the dirty string warning on the given logger, got %+v", hook.AllEntries())
+	}
 }
 
 // TestVersionOutput_JSON tests the VersionOutputToString function with a full
@@ -102,7 +118,7 @@ func TestReturnJsonVersion_Valid(t *testing.T) {
 	RawGitDescribe = "v1.2.3"
 	GitDirtyStr = "false"
 
-	out, err := returnJsonVersion(false)
+	out, err := returnJsonVersion(discardLogger, false)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -120,7 +136,7 @@ func TestTopLevelKey_JSON(t *testing.T) {
 	RawGitDescribe = "v0.3.0"
 	GitDirtyStr = "true"
 
-	data, err := returnJsonVersion(false)
+	data, err := returnJsonVersion(discardLogger, false)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -135,7 +151,7 @@ func TestTopLevelKey_YAML(t *testing.T) {
 	RawGitDescribe = "v0.3.0"
 	GitDirtyStr = "true"
 
-	data, err := returnYamlVersion()
+	data, err := returnYamlVersion(discardLogger)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -156,7 +172,7 @@ func TestVersionDetailsRoundTrip(t *testing.T) {
 	BuildDate = "2025-04-15T01:00:00Z"
 	BuildPlatform = "x86_64"
 
-	jsonData, err := returnJsonVersion(false)
+	jsonData, err := returnJsonVersion(discardLogger, false)
 	if err != nil {
 		t.Fatalf("Error generating JSON: %v", err)
 	}
@@ -185,3 +201,172 @@ func TestIsPopulated(t *testing.T) {
 		t.Error("Expected IsPopulated to be true when RawGitDescribe is set")
 	}
 }
+
+// TestVersionOutput_Formats tests the VersionOutputToString function with the
+// template, template-file, jsonpath, env and short formats, and that an
+// unknown format falls back to the one liner.
+func TestVersionOutput_Formats(t *testing.T) {
+	resetGlobals()
+	RawGitDescribe = "v1.4.2"
+	GitDirtyStr = "false"
+	GitCommitIdShort = "abc123"
+	BuildPlatform = "linux/amd64"
+	BuildDate = "2025-01-01 01:00:00"
+
+	templateFile := filepath.Join(t.TempDir(), "version.tmpl")
+	if err := os.WriteFile(templateFile, []byte("{{.Version}} ({{.GitCommitIdShort}})\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{"template={{.Major}}.{{.Minor}}", "1.4"},
+		{"template-file=" + templateFile, "v1.4.2 (abc123)"},
+		{"jsonpath={.cobravsviper.gitCommitIdShort}", "abc123"},
+		{"short", "v1.4.2"},
+		{"env", `COBRAVSVIPER_MAJOR=1
+COBRAVSVIPER_MINOR=4
+COBRAVSVIPER_PATCH=2
+COBRAVSVIPER_PRERELEASE=
+COBRAVSVIPER_METADATA=
+COBRAVSVIPER_VERSION=v1.4.2
+COBRAVSVIPER_CLOSEST_TAG=v1.4.2
+COBRAVSVIPER_COMMITS_SINCE_TAG=0
+COBRAVSVIPER_IS_GIT_DIRTY=false
+COBRAVSVIPER_GIT_COMMIT_ID_LONG=
+COBRAVSVIPER_GIT_COMMIT_ID_SHORT=abc123
+COBRAVSVIPER_GIT_COMMIT_TIMESTAMP=
+COBRAVSVIPER_GO_VERSION=
+COBRAVSVIPER_BUILD_DATE='2025-01-01 01:00:00'
+COBRAVSVIPER_BUILD_PLATFORM=linux/amd64`},
+		{"xml", "cobravsviper: 1.4.2"},
+		{"template={{.Unknown}}", "Error generating template output"},
+	}
+
+	for _, tc := range cases {
+		if out := VersionOutputToString(tc.format, true); !strings.HasPrefix(out, tc.expected) {
+			t.Errorf("VersionOutputToString(%q) = %q, expected %q", tc.format, out, tc.expected)
+		}
+	}
+}
+
+// TestCheck tests the Check function against constraints, with prereleases,
+// snapshot and dirty builds allowed or not, and invalid constraints.
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		version       string
+		dirty         bool
+		constraints   string
+		allowSnapshot bool
+		allowDirty    bool
+		expected      string // "" on success, "invalid" for an invalid constraint, the CheckError otherwise
+	}{
+		{"v1.4.2", false, ">= 1.4, < 2.0", false, false, ""},
+		{"v1.4.2-5-gabc123", false, ">= 1.4, < 2.0", false, false, ""},
+		{"v1.3.9", false, ">= 1.4, < 2.0", false, false, "version v1.3.9 does not satisfy >= 1.4, < 2.0"},
+		{"v2.0.0", false, ">= 1.4, < 2.0", false, false, "version v2.0.0 does not satisfy >= 1.4, < 2.0"},
+		{"v1.4.2", false, "~> 1.4.0", false, false, ""},
+		{"v1.4.0-rc.1", false, ">= 1.4", false, false, "version v1.4.0-rc.1 does not satisfy >= 1.4"},
+		{"v1.4.0-rc.1-3-gabc123", false, ">= 1.4", false, false, "version v1.4.0-rc.1-3-gabc123 does not satisfy >= 1.4"},
+		{"v2.0.0-rc.1", false, ">= 1.4, < 2.0", false, false, ""},
+		{"v1.4.0-rc.2", false, ">= 1.4.0-rc.1", false, false, ""},
+		{"v1.4.1-rc.1", false, "~> 1.4.0", false, false, ""},
+		{"v1.4.0-rc.1", false, "~> 1.4.0", false, false, "version v1.4.0-rc.1 does not satisfy ~> 1.4.0"},
+		{"abc123", false, ">= 1.4", false, false, `version "abc123" is a snapshot build, not checked against >= 1.4`},
+		{"abc123", false, ">= 1.4", false, true, `version "abc123" is a snapshot build, not checked against >= 1.4`},
+		{"abc123", false, ">= 1.4", true, false, ""},
+		{"v1.4.2", true, ">= 1.4", false, false, "version v1.4.2 is a dirty build, not checked against >= 1.4"},
+		{"v1.4.2", true, ">= 1.4", true, false, "version v1.4.2 is a dirty build, not checked against >= 1.4"},
+		{"v1.4.2", true, ">= 1.4", false, true, ""},
+		{"v1.3.0", true, ">= 1.4", false, true, "version v1.3.0 does not satisfy >= 1.4"},
+		{"v1.4.2", false, "at least 1.4", false, false, "invalid"},
+	}
+
+	for _, tc := range cases {
+		resetGlobals()
+		RawGitDescribe = tc.version
+		GitDirtyStr = strconv.FormatBool(tc.dirty)
+		versionData, _ := NewVersionData()
+
+		err := Check(versionData, tc.constraints, tc.allowSnapshot, tc.allowDirty)
+		var checkErr *CheckError
+		switch {
+		case tc.expected == "" && err != nil:
+			t.Errorf("Check(%s, %q) returned error: %v", tc.version, tc.constraints, err)
+		case tc.expected == "invalid" && (err == nil || errors.As(err, &checkErr)):
+			t.Errorf("Check(%s, %q) = %v, expected an invalid constraint error", tc.version, tc.constraints, err)
+		case tc.expected != "" && tc.expected != "invalid" && (!errors.As(err, &checkErr) || err.Error() != tc.expected):
+			t.Errorf("Check(%s, %q) = %v, expected %q", tc.version, tc.constraints, err, tc.expected)
+		}
+	}
+}
+
+// TestNewVersionData_GitDescribe tests the NewVersionData function with the
+// shapes of git describe --tags --always --dirty: a tag, with a prerelease or
+// build metadata, followed by the commits since the tag, or a commit hash
+// without tag.
+func TestNewVersionData_GitDescribe(t *testing.T) {
+	cases := []struct {
+		describe string
+		expected VersionData
+	}{
+		{"v1.2.3", VersionData{Major: 1, Minor: 2, Patch: 3, ClosestTag: "v1.2.3"}},
+		{"1.2.3", VersionData{Major: 1, Minor: 2, Patch: 3, ClosestTag: "1.2.3"}},
+		{"v1.2.3-rc.1", VersionData{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", ClosestTag: "v1.2.3-rc.1"}},
+		{"v1.2.3+build.7", VersionData{Major: 1, Minor: 2, Patch: 3, Metadata: "build.7", ClosestTag: "v1.2.3+build.7"}},
+		{"v1.2.3-rc.1+build.7", VersionData{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Metadata: "build.7", ClosestTag: "v1.2.3-rc.1+build.7"}},
+		{"v1.2.3-5-gabc123", VersionData{Major: 1, Minor: 2, Patch: 3, ClosestTag: "v1.2.3", CommitsSinceTag: 5}},
+		{"v1.2.3-rc.1-5-gabc123", VersionData{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", ClosestTag: "v1.2.3-rc.1", CommitsSinceTag: 5}},
+		{"v1.2.3-rc-1-12-gabc123", VersionData{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc-1", ClosestTag: "v1.2.3-rc-1", CommitsSinceTag: 12}},
+		{"v1.2.3-rc.1+build.7-5-gabc123", VersionData{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Metadata: "build.7", ClosestTag: "v1.2.3-rc.1+build.7", CommitsSinceTag: 5}},
+		{"v1.2.3-dirty", VersionData{Major: 1, Minor: 2, Patch: 3, ClosestTag: "v1.2.3"}},
+		{"v1.2.3-5-gabc123-dirty", VersionData{Major: 1, Minor: 2, Patch: 3, ClosestTag: "v1.2.3", CommitsSinceTag: 5}},
+		{"v0.10.0-120-g0123456789abcdef", VersionData{Minor: 10, ClosestTag: "v0.10.0", CommitsSinceTag: 120}},
+		{"abc123", VersionData{}},
+		{"abc123-dirty", VersionData{}},
+		{"1234567", VersionData{}},
+		{"1234567-dirty", VersionData{}},
+		{"v1.2", VersionData{}},
+		{"release-5-gabc123", VersionData{}},
+		{"", VersionData{}},
+	}
+
+	for _, tc := range cases {
+		resetGlobals()
+		RawGitDescribe = tc.describe
+		GitDirtyStr = "false"
+		tc.expected.Version = tc.describe
+
+		versionData, err := NewVersionData()
+		if err != nil {
+			t.Errorf("NewVersionData() with %q returned error: %v", tc.describe, err)
+			continue
+		}
+		if versionData != tc.expected {
+			t.Errorf("NewVersionData() with %q = %+v, expected %+v", tc.describe, versionData, tc.expected)
+		}
+	}
+}
+
+// TestVersionOutput_GitDescribeFields tests the fields parsed from the git
+// describe are in the JSON and YAML outputs.
+func TestVersionOutput_GitDescribeFields(t *testing.T) {
+	resetGlobals()
+	RawGitDescribe = "v1.2.3-rc.1+build.7-5-gabc123"
+	GitDirtyStr = "false"
+
+	jsonOut := VersionOutputToString("json", false)
+	for _, want := range []string{`"prerelease":"rc.1"`, `"metadata":"build.7"`, `"closestTag":"v1.2.3-rc.1+build.7"`, `"commitsSinceTag":5`} {
+		if !strings.Contains(jsonOut, want) {
+			t.Errorf("JSON output missing %s: %s", want, jsonOut)
+		}
+	}
+	yamlOut := VersionOutputToString("yaml", true)
+	for _, want := range []string{"prerelease: rc.1", "metadata: build.7", "closestTag: v1.2.3-rc.1+build.7", "commitsSinceTag: 5"} {
+		if !strings.Contains(yamlOut, want) {
+			t.Errorf("YAML output missing %s: %s", want, yamlOut)
+		}
+	}
+}